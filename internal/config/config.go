@@ -1,36 +1,287 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds the application configuration
 type Config struct {
 	GitHubToken string
-	GitHubOwner string
+	// RefreshToken reads the token again when it comes from a token file or command, and
+	// is nil otherwise
+	RefreshToken func() (string, error)
+	// App is set when authenticating as a GitHub App instead of with a token
+	App          *App
+	GitHubOwner  string
+	Repos        []string
+	ExcludeRepos []string
 }
 
-// Load loads the configuration from environment variables
-func Load() (*Config, error) {
-	if err := godotenv.Load(); err != nil {
-		return nil, fmt.Errorf("error loading .env file: %v", err)
+// App configures authentication as a GitHub App installation
+type App struct {
+	ID int64 `yaml:"id"`
+	// InstallationID is looked up from the owner when zero
+	InstallationID int64 `yaml:"installation_id"`
+	// PrivateKey is the PEM encoded private key of the app; after loading it is always
+	// set, also when the key is read from PrivateKeyFile
+	PrivateKey     string `yaml:"private_key"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+}
+
+// File is a parsed configuration file. Besides the settings below, it holds defaults
+// for command flags in sections named after the commands, e.g.
+//
+//	list:
+//	  days: 14
+//	notify:
+//	  slack:
+//	    webhook_url: https://hooks.slack.com/services/...
+type File struct {
+	Token string `yaml:"token"`
+	// TokenFile names a file holding the token
+	TokenFile string `yaml:"token_file"`
+	// TokenCommand is a shell command printing the token, e.g. a secrets manager CLI
+	TokenCommand string              `yaml:"token_command"`
+	App          *App                `yaml:"app"`
+	Owner        string              `yaml:"owner"`
+	Repos        []string            `yaml:"repos"`
+	ExcludeRepos []string            `yaml:"exclude_repos"`
+	Profiles     map[string]*Profile `yaml:"profiles"`
+
+	values   map[string]any
+	selected *Profile
+}
+
+// Profile is a named set of settings, e.g. for a personal account and a work organization.
+// A selected profile overrides the rest of the file, and its token and owner also override
+// GITHUB_TOKEN and GITHUB_OWNER, so that switching profiles is never undone by the environment.
+type Profile struct {
+	Token string `yaml:"token"`
+	// TokenEnv names an environment variable holding the token
+	TokenEnv     string   `yaml:"token_env"`
+	TokenFile    string   `yaml:"token_file"`
+	TokenCommand string   `yaml:"token_command"`
+	App          *App     `yaml:"app"`
+	Owner        string   `yaml:"owner"`
+	Repos        []string `yaml:"repos"`
+	ExcludeRepos []string `yaml:"exclude_repos"`
+
+	values map[string]any
+}
+
+// DefaultPath returns the default configuration file path, following the XDG base directory
+// spec on every platform, unlike os.UserConfigDir
+func DefaultPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh-workflow-monitor", "config.yaml"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding home directory: %v", err)
+	}
+	return filepath.Join(home, ".config", "gh-workflow-monitor", "config.yaml"), nil
+}
+
+// LoadFile reads a configuration file. A missing file is only an error when it is required,
+// otherwise an empty configuration is returned.
+func LoadFile(path string, required bool) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !required && errors.Is(err, fs.ErrNotExist) {
+			return &File{}, nil
+		}
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	return ParseFile(data)
+}
+
+// ParseFile parses the contents of a configuration file
+func ParseFile(data []byte) (*File, error) {
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing config file: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &file.values); err != nil {
+		return nil, fmt.Errorf("error parsing config file: %v", err)
 	}
 
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return nil, fmt.Errorf("GITHUB_TOKEN environment variable is required")
+	if err := checkTokenSettings("config file", map[string]string{
+		"token":         file.Token,
+		"token_file":    file.TokenFile,
+		"token_command": file.TokenCommand,
+	}); err != nil {
+		return nil, err
 	}
 
-	owner := os.Getenv("GITHUB_OWNER")
-	if owner == "" {
-		return nil, fmt.Errorf("GITHUB_OWNER environment variable is required")
+	profiles, _ := file.values["profiles"].(map[string]any)
+	for name, profile := range file.Profiles {
+		if profile == nil {
+			profile = &Profile{}
+			file.Profiles[name] = profile
+		}
+		profile.values, _ = profiles[name].(map[string]any)
+
+		if err := checkTokenSettings(fmt.Sprintf("profile %q", name), map[string]string{
+			"token":         profile.Token,
+			"token_env":     profile.TokenEnv,
+			"token_file":    profile.TokenFile,
+			"token_command": profile.TokenCommand,
+		}); err != nil {
+			return nil, err
+		}
 	}
 
-	return &Config{
-		GitHubToken: token,
-		GitHubOwner: owner,
-	}, nil
+	return &file, nil
+}
+
+// SelectProfile applies the named profile to the rest of the file
+func (f *File) SelectProfile(name string) error {
+	profile, ok := f.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q is not defined in the config file", name)
+	}
+	f.selected = profile
+	return nil
+}
+
+// Lookup returns the value of a key in the section for the given command path, e.g.
+// Lookup([]string{"notify", "slack"}, "webhook_url"), preferring the selected profile.
+// Dashes and underscores in keys are interchangeable.
+func (f *File) Lookup(section []string, key string) (any, bool) {
+	if f.selected != nil {
+		if value, ok := lookupSection(f.selected.values, section, key); ok {
+			return value, true
+		}
+	}
+	return lookupSection(f.values, section, key)
+}
+
+func lookupSection(values map[string]any, section []string, key string) (any, bool) {
+	for _, name := range section {
+		next, ok := lookup(values, name).(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		values = next
+	}
+
+	value := lookup(values, key)
+	return value, value != nil
+}
+
+func lookup(values map[string]any, key string) any {
+	if value, ok := values[key]; ok {
+		return value
+	}
+	if value, ok := values[strings.ReplaceAll(key, "-", "_")]; ok {
+		return value
+	}
+	return values[strings.ReplaceAll(key, "_", "-")]
+}
+
+// LoadDotEnv loads environment variables from a .env file in the current directory, if
+// there is one. Variables that are already set are not overridden.
+func LoadDotEnv() error {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error loading .env file: %v", err)
+	}
+	return nil
+}
+
+// Load loads the configuration from environment variables, falling back to the
+// configuration file and then to the gh CLI's login. The owner is empty when none is
+// configured. file may be nil. baseURL is the API URL of a GitHub Enterprise Server, if any,
+// whose gh login is used.
+func Load(file *File, baseURL string) (*Config, error) {
+	if file == nil {
+		file = &File{}
+	}
+
+	cfg := &Config{
+		GitHubToken:  file.Token,
+		GitHubOwner:  Owner(file),
+		Repos:        file.Repos,
+		ExcludeRepos: file.ExcludeRepos,
+	}
+	refresh := tokenSource(file.TokenFile, file.TokenCommand)
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		cfg.GitHubToken, refresh = token, nil
+	}
+
+	if p := file.selected; p != nil {
+		if p.TokenEnv != "" {
+			cfg.GitHubToken, refresh = os.Getenv(p.TokenEnv), nil
+			if cfg.GitHubToken == "" {
+				return nil, fmt.Errorf("environment variable %s of the profile is not set", p.TokenEnv)
+			}
+		}
+		if p.Token != "" {
+			cfg.GitHubToken, refresh = p.Token, nil
+		}
+		if source := tokenSource(p.TokenFile, p.TokenCommand); source != nil {
+			refresh = source
+		}
+		if p.Repos != nil || p.ExcludeRepos != nil {
+			cfg.Repos, cfg.ExcludeRepos = p.Repos, p.ExcludeRepos
+		}
+	}
+
+	app, err := loadApp(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case app != nil:
+		// An app replaces any token
+		cfg.App, cfg.GitHubToken = app, ""
+	case refresh != nil:
+		// Token files and commands are read once here; the client only reads them again
+		// when the token is rejected
+		token, err := refresh()
+		if err != nil {
+			return nil, err
+		}
+		cfg.GitHubToken, cfg.RefreshToken = token, refresh
+	}
+
+	// Fall back to the credentials of the gh CLI, so that the tool works as a gh
+	// extension without any configuration
+	if cfg.App == nil && cfg.GitHubToken == "" {
+		cfg.GitHubToken = os.Getenv("GH_TOKEN")
+	}
+	if cfg.App == nil && cfg.GitHubToken == "" {
+		cfg.GitHubToken = GHToken(ghHostname(baseURL))
+	}
+	if cfg.App == nil && cfg.GitHubToken == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN environment variable, token setting, app setting or gh login is required")
+	}
+
+	return cfg, nil
+}
+
+// Owner returns the configured owner: the selected profile's, GITHUB_OWNER or the file's.
+// It is empty when none is configured. file may be nil.
+func Owner(file *File) string {
+	if file == nil {
+		file = &File{}
+	}
+
+	if p := file.selected; p != nil && p.Owner != "" {
+		return p.Owner
+	}
+	if owner := os.Getenv("GITHUB_OWNER"); owner != "" {
+		return owner
+	}
+	return file.Owner
 }
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ghHost is the entry for one host in the gh CLI's hosts.yml
+type ghHost struct {
+	User       string `yaml:"user"`
+	OAuthToken string `yaml:"oauth_token"`
+	Users      map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	} `yaml:"users"`
+}
+
+// GHToken returns the token the gh CLI is logged in with for a host, or an empty string
+// when gh is not installed or not logged in
+func GHToken(host string) string {
+	if token := ghHostsToken(host); token != "" {
+		return token
+	}
+
+	// Recent gh versions keep the token in the system keyring instead of hosts.yml
+	out, err := exec.Command("gh", "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// ghHostsToken reads the token for a host from the gh CLI's hosts.yml
+func ghHostsToken(host string) string {
+	dir := ghConfigDir()
+	if dir == "" {
+		return ""
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+
+	var hosts map[string]ghHost
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return ""
+	}
+
+	entry := hosts[host]
+	if entry.OAuthToken != "" {
+		return entry.OAuthToken
+	}
+	return entry.Users[entry.User].OAuthToken
+}
+
+// ghConfigDir returns the directory the gh CLI keeps its configuration in, which unlike
+// os.UserConfigDir is ~/.config on macOS too
+func ghConfigDir() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh")
+	}
+	if dir := os.Getenv("AppData"); runtime.GOOS == "windows" && dir != "" {
+		return filepath.Join(dir, "GitHub CLI")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh")
+}
+
+// ghHostname returns the host to use the gh login of: GH_HOST, or else the host of the
+// API base URL
+func ghHostname(baseURL string) string {
+	if host := os.Getenv("GH_HOST"); host != "" {
+		return host
+	}
+	if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" && u.Hostname() != "api.github.com" {
+		return u.Hostname()
+	}
+	return "github.com"
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// tokenSource returns a function reading the token from a file or the output of a shell
+// command, or nil when neither is set
+func tokenSource(file, command string) func() (string, error) {
+	switch {
+	case file != "":
+		return func() (string, error) { return readSecretFile(file) }
+	case command != "":
+		return func() (string, error) { return runTokenCommand(command) }
+	default:
+		return nil
+	}
+}
+
+// readSecretFile reads a token or key from a file, trimming surrounding whitespace. A
+// leading ~/ is expanded to the home directory.
+func readSecretFile(path string) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("error reading %s: %v", path, err)
+		}
+		path = filepath.Join(home, rest)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return secret, nil
+}
+
+// runTokenCommand runs a shell command, e.g. a secrets manager CLI, and returns its output
+func runTokenCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("error running token command: %v: %s", err, msg)
+		}
+		return "", fmt.Errorf("error running token command: %v", err)
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("token command printed no token")
+	}
+	return token, nil
+}
+
+// checkTokenSettings reports an error when more than one way of providing the token is set
+func checkTokenSettings(scope string, settings map[string]string) error {
+	var set []string
+	for _, name := range []string{"token", "token_env", "token_file", "token_command"} {
+		if settings[name] != "" {
+			set = append(set, name)
+		}
+	}
+	if len(set) > 1 {
+		return fmt.Errorf("%s sets %s: only one of them may be set", scope, strings.Join(set, " and "))
+	}
+	return nil
+}
+
+// loadApp resolves the GitHub App to authenticate as from the file, the GITHUB_APP_*
+// environment variables and the selected profile, and reads its private key. It returns
+// nil when no app is configured, or when GITHUB_TOKEN overrides the app of the file.
+func loadApp(file *File) (*App, error) {
+	var app App
+	if file.App != nil {
+		app = *file.App
+	}
+	// A token exported in the environment overrides an app set only in the file, like it
+	// overrides the file's token
+	fileOnly := file.App != nil && os.Getenv("GITHUB_APP_ID") == ""
+
+	// A profile with its own credentials replaces the app of the rest of the file
+	if p := file.selected; p != nil {
+		switch {
+		case p.App != nil:
+			app, fileOnly = *p.App, false
+		case p.Token != "" || p.TokenEnv != "" || p.TokenFile != "" || p.TokenCommand != "":
+			return nil, nil
+		}
+	}
+
+	// The GITHUB_APP_* variables override single fields of the app from the file or profile
+	for name, target := range map[string]*int64{
+		"GITHUB_APP_ID":              &app.ID,
+		"GITHUB_APP_INSTALLATION_ID": &app.InstallationID,
+	} {
+		if value := os.Getenv(name); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*target = id
+		}
+	}
+	if value := os.Getenv("GITHUB_APP_PRIVATE_KEY"); value != "" {
+		app.PrivateKey, app.PrivateKeyFile = value, ""
+	}
+	if value := os.Getenv("GITHUB_APP_PRIVATE_KEY_FILE"); value != "" {
+		app.PrivateKey, app.PrivateKeyFile = "", value
+	}
+
+	if fileOnly && os.Getenv("GITHUB_TOKEN") != "" {
+		return nil, nil
+	}
+
+	if app.ID == 0 {
+		return nil, nil
+	}
+
+	if app.PrivateKey == "" {
+		if app.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("app %d has no private key: set private_key_file or GITHUB_APP_PRIVATE_KEY_FILE", app.ID)
+		}
+		key, err := readSecretFile(app.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading app private key: %v", err)
+		}
+		app.PrivateKey = key
+	}
+
+	return &app, nil
+}
@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultHost is the host of repositories that do not name one
+const DefaultHost = "github.com"
+
+// Repo identifies a GitHub repository
+type Repo struct {
+	Host  string
+	Owner string
+	Name  string
+}
+
+// FullName returns the repository as owner/name
+func (r Repo) FullName() string {
+	return r.Owner + "/" + r.Name
+}
+
+var (
+	namePattern  = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	ownerPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+	hostPattern  = regexp.MustCompile(`^[A-Za-z0-9.-]+(:[0-9]+)?$`)
+)
+
+// Parse parses a repository in the [HOST/]OWNER/REPO format used by GH_REPO. The host
+// is empty when it is not given.
+func Parse(value string) (Repo, error) {
+	r, err := ParseArg(value)
+	if err != nil {
+		return Repo{}, err
+	}
+	if r.Owner == "" {
+		return Repo{}, fmt.Errorf("invalid repository %q: expected [HOST/]OWNER/REPO", value)
+	}
+	return r, nil
+}
+
+// ParseArg parses a repository given on the command line as NAME, OWNER/NAME or
+// HOST/OWNER/NAME. Owner and host are empty when they are not given.
+func ParseArg(value string) (Repo, error) {
+	var r Repo
+	parts := strings.Split(value, "/")
+	switch len(parts) {
+	case 1:
+		r.Name = parts[0]
+	case 2:
+		r.Owner, r.Name = parts[0], parts[1]
+	case 3:
+		r.Host, r.Owner, r.Name = parts[0], parts[1], parts[2]
+	default:
+		return Repo{}, fmt.Errorf("invalid repository %q: expected NAME, OWNER/NAME or HOST/OWNER/NAME", value)
+	}
+
+	switch {
+	case !namePattern.MatchString(r.Name) || r.Name == "." || r.Name == "..":
+		return Repo{}, fmt.Errorf("invalid repository %q: %q is not a valid repository name", value, r.Name)
+	case len(parts) > 1 && !ownerPattern.MatchString(r.Owner):
+		return Repo{}, fmt.Errorf("invalid repository %q: %q is not a valid owner", value, r.Owner)
+	case len(parts) > 2 && !hostPattern.MatchString(r.Host):
+		return Repo{}, fmt.Errorf("invalid repository %q: %q is not a valid host", value, r.Host)
+	}
+
+	return r, nil
+}
+
+// ParseRemoteURL parses a git remote URL, e.g. git@github.com:owner/repo.git or
+// https://github.com/owner/repo
+func ParseRemoteURL(remote string) (Repo, error) {
+	var host, path string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return Repo{}, fmt.Errorf("invalid remote URL %q: %v", remote, err)
+		}
+		host, path = u.Hostname(), u.Path
+	} else {
+		// scp-like syntax: [user@]host:path
+		var ok bool
+		host, path, ok = strings.Cut(remote, ":")
+		if !ok {
+			return Repo{}, fmt.Errorf("invalid remote URL %q", remote)
+		}
+		if _, after, found := strings.Cut(host, "@"); found {
+			host = after
+		}
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	owner, name, ok := strings.Cut(path, "/")
+	if host == "" || !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return Repo{}, fmt.Errorf("remote URL %q does not point to a GitHub repository", remote)
+	}
+
+	return Repo{Host: host, Owner: owner, Name: name}, nil
+}
+
+// Detect returns the repository named by GH_REPO, or else the one the origin remote of
+// the git checkout in the current directory points to
+func Detect() (Repo, error) {
+	if value := os.Getenv("GH_REPO"); value != "" {
+		return Parse(value)
+	}
+
+	out, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	if err != nil {
+		return Repo{}, fmt.Errorf("error detecting repository: not in a git checkout with an origin remote and GH_REPO is not set")
+	}
+
+	return ParseRemoteURL(strings.TrimSpace(string(out)))
+}
+
+// ParsePullRequestURL parses the URL of a pull request, e.g.
+// https://github.com/owner/repo/pull/123, returning its repository and number
+func ParsePullRequestURL(value string) (Repo, int, error) {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return Repo{}, 0, fmt.Errorf("invalid pull request URL %q", value)
+	}
+
+	// Links to a tab of the pull request, e.g. /pull/123/files, are accepted too
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || parts[2] != "pull" || parts[0] == "" || parts[1] == "" {
+		return Repo{}, 0, fmt.Errorf("invalid pull request URL %q: expected https://HOST/OWNER/REPO/pull/NUMBER", value)
+	}
+	number, err := strconv.Atoi(parts[3])
+	if err != nil || number <= 0 {
+		return Repo{}, 0, fmt.Errorf("invalid pull request URL %q: %q is not a pull request number", value, parts[3])
+	}
+
+	return Repo{Host: u.Hostname(), Owner: parts[0], Name: parts[1]}, number, nil
+}
+
+// CurrentBranch returns the branch checked out in the current directory
+func CurrentBranch() (string, error) {
+	out, err := exec.Command("git", "symbolic-ref", "--quiet", "--short", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("error detecting branch: not in a git checkout, or no branch is checked out")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// PushRemote returns the repository that git pushes the branch to in the current
+// directory: the branch's push remote, the default push remote, its upstream remote or
+// origin, in that order
+func PushRemote(branch string) (Repo, error) {
+	remote := "origin"
+	for _, key := range []string{"branch." + branch + ".pushRemote", "remote.pushDefault", "branch." + branch + ".remote"} {
+		out, err := exec.Command("git", "config", "--get", key).Output()
+		if value := strings.TrimSpace(string(out)); err == nil && value != "" {
+			remote = value
+			break
+		}
+	}
+
+	out, err := exec.Command("git", "remote", "get-url", "--push", remote).Output()
+	if err != nil {
+		return Repo{}, fmt.Errorf("error detecting push remote: %s is not a remote of the git checkout", remote)
+	}
+
+	return ParseRemoteURL(strings.TrimSpace(string(out)))
+}
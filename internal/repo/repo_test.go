@@ -0,0 +1,185 @@
+package repo_test
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    repo.Repo
+		wantErr bool
+	}{
+		{input: "acme/api", want: repo.Repo{Owner: "acme", Name: "api"}},
+		{input: "ghe.example.com/acme/api", want: repo.Repo{Host: "ghe.example.com", Owner: "acme", Name: "api"}},
+		{input: "api", wantErr: true},
+		{input: "acme/", wantErr: true},
+		{input: "a/b/c/d", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := repo.Parse(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseArg(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    repo.Repo
+		wantErr bool
+	}{
+		{input: "api", want: repo.Repo{Name: "api"}},
+		{input: "my.repo_v2-beta", want: repo.Repo{Name: "my.repo_v2-beta"}},
+		{input: "acme/api", want: repo.Repo{Owner: "acme", Name: "api"}},
+		{input: "ghe.example.com:8443/acme/api", want: repo.Repo{Host: "ghe.example.com:8443", Owner: "acme", Name: "api"}},
+		{input: "", wantErr: true},
+		{input: "/api", wantErr: true},
+		{input: "acme/", wantErr: true},
+		{input: "-acme/api", wantErr: true},
+		{input: "acme/my repo", wantErr: true},
+		{input: "..", wantErr: true},
+		{input: "ghe example/acme/api", wantErr: true},
+		{input: "https://github.com/acme/api", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := repo.ParseArg(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    repo.Repo
+		wantErr bool
+	}{
+		{input: "git@github.com:acme/api.git", want: repo.Repo{Host: "github.com", Owner: "acme", Name: "api"}},
+		{input: "https://github.com/acme/api.git", want: repo.Repo{Host: "github.com", Owner: "acme", Name: "api"}},
+		{input: "https://github.com/acme/api", want: repo.Repo{Host: "github.com", Owner: "acme", Name: "api"}},
+		{input: "ssh://git@ghe.example.com:2222/acme/api.git", want: repo.Repo{Host: "ghe.example.com", Owner: "acme", Name: "api"}},
+		{input: "https://token@github.com/acme/api/", want: repo.Repo{Host: "github.com", Owner: "acme", Name: "api"}},
+		{input: "/srv/git/api.git", wantErr: true},
+		{input: "https://github.com/acme", wantErr: true},
+		{input: "https://github.com/acme/api/tree/main", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := repo.ParseRemoteURL(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParsePullRequestURL(t *testing.T) {
+	tests := []struct {
+		input      string
+		want       repo.Repo
+		wantNumber int
+		wantErr    bool
+	}{
+		{input: "https://github.com/acme/api/pull/123", want: repo.Repo{Host: "github.com", Owner: "acme", Name: "api"}, wantNumber: 123},
+		{input: "https://ghe.example.com/acme/api/pull/7/files", want: repo.Repo{Host: "ghe.example.com", Owner: "acme", Name: "api"}, wantNumber: 7},
+		{input: "https://github.com/acme/api/issues/123", wantErr: true},
+		{input: "https://github.com/acme/api/pull/abc", wantErr: true},
+		{input: "github.com/acme/api/pull/123", wantErr: true},
+		{input: "123", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, number, err := repo.ParsePullRequestURL(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantNumber, number)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("GH_REPO", "acme/api")
+	got, err := repo.Detect()
+	require.NoError(t, err)
+	assert.Equal(t, "acme/api", got.FullName())
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"remote", "add", "origin", "git@github.com:octocat/hello-world.git"},
+		{"checkout", "-q", "-b", "fix-flaky-test"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		require.NoError(t, cmd.Run())
+	}
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	t.Setenv("GH_REPO", "")
+	got, err = repo.Detect()
+	require.NoError(t, err)
+	assert.Equal(t, repo.Repo{Host: "github.com", Owner: "octocat", Name: "hello-world"}, got)
+
+	branch, err := repo.CurrentBranch()
+	require.NoError(t, err)
+	assert.Equal(t, "fix-flaky-test", branch)
+
+	// Branches are pushed to origin unless git is told otherwise
+	remote, err := repo.PushRemote(branch)
+	require.NoError(t, err)
+	assert.Equal(t, "octocat", remote.Owner)
+
+	for _, args := range [][]string{
+		{"remote", "add", "fork", "https://github.com/hubot/hello-world.git"},
+		{"config", "branch.fix-flaky-test.pushRemote", "fork"},
+	} {
+		require.NoError(t, exec.Command("git", args...).Run())
+	}
+	remote, err = repo.PushRemote(branch)
+	require.NoError(t, err)
+	assert.Equal(t, repo.Repo{Host: "github.com", Owner: "hubot", Name: "hello-world"}, remote)
+
+	_, err = repo.PushRemote("missing")
+	assert.NoError(t, err, "branches without settings are pushed to origin")
+	require.NoError(t, exec.Command("git", "remote", "remove", "origin").Run())
+	_, err = repo.PushRemote("missing")
+	assert.Error(t, err)
+}
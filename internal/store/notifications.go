@@ -0,0 +1,168 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The store keeps the notification state of the notify commands
+var _ notify.State = (*Store)(nil)
+
+// Snooze silences notifications for a repository, or a single workflow of it, until a time
+type Snooze struct {
+	Repo string `json:"repo"`
+	// Workflow is empty when every workflow of the repository is snoozed
+	Workflow string    `json:"workflow,omitempty"`
+	Until    time.Time `json:"until"`
+}
+
+// Unresolved returns the failures handled by the notifier whose workflow has not recovered yet, oldest first
+func (s *Store) Unresolved(notifier string) ([]notify.Notification, error) {
+	var result []notify.Notification
+	prefix := []byte(notifier + "\x00")
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(notificationsBucket).Cursor()
+		for key, value := c.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = c.Next() {
+			var n notify.Notification
+			// Skip records that predate the notification state
+			if err := json.Unmarshal(value, &n); err != nil || n.Repo == "" {
+				continue
+			}
+			if !n.Resolved() {
+				result = append(result, n)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading notifications: %v", err)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.Before(result[j].StartedAt)
+	})
+	return result, nil
+}
+
+// MarkResolved records that the workflow of a failed run handled by the notifier has recovered
+func (s *Store) MarkResolved(notifier string, runID int64, at time.Time) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(notificationsBucket)
+		key := notificationKey(notifier, runID)
+
+		var n notify.Notification
+		value := bucket.Get(key)
+		if value == nil {
+			return fmt.Errorf("run %d has not been announced", runID)
+		}
+		if err := json.Unmarshal(value, &n); err != nil {
+			return err
+		}
+
+		n.ResolvedAt = at
+		value, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("error saving notification: %v", err)
+	}
+
+	return nil
+}
+
+// SaveSnooze silences notifications for a repository or workflow, replacing any earlier snooze
+func (s *Store) SaveSnooze(snooze Snooze) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		value, err := json.Marshal(snooze)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(snoozesBucket).Put(snoozeKey(snooze.Repo, snooze.Workflow), value)
+	})
+	if err != nil {
+		return fmt.Errorf("error saving snooze: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteSnooze removes the snooze of a repository or workflow
+func (s *Store) DeleteSnooze(repo, workflow string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(snoozesBucket).Delete(snoozeKey(repo, workflow))
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting snooze: %v", err)
+	}
+
+	return nil
+}
+
+// Snoozes returns the snoozes that are still active at the given time, sorted by repository and workflow
+func (s *Store) Snoozes(at time.Time) ([]Snooze, error) {
+	var snoozes []Snooze
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(snoozesBucket).ForEach(func(_, value []byte) error {
+			var snooze Snooze
+			if err := json.Unmarshal(value, &snooze); err != nil {
+				return err
+			}
+			if snooze.Until.After(at) {
+				snoozes = append(snoozes, snooze)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading snoozes: %v", err)
+	}
+
+	sort.Slice(snoozes, func(i, j int) bool {
+		if snoozes[i].Repo != snoozes[j].Repo {
+			return snoozes[i].Repo < snoozes[j].Repo
+		}
+		return snoozes[i].Workflow < snoozes[j].Workflow
+	})
+	return snoozes, nil
+}
+
+// Snoozed reports whether notifications for the workflow are silenced at the given time,
+// either for the workflow itself or for its whole repository
+func (s *Store) Snoozed(repo, workflow string, at time.Time) (bool, error) {
+	snoozed := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(snoozesBucket)
+		for _, key := range [][]byte{snoozeKey(repo, ""), snoozeKey(repo, workflow)} {
+			value := bucket.Get(key)
+			if value == nil {
+				continue
+			}
+			var snooze Snooze
+			if err := json.Unmarshal(value, &snooze); err != nil {
+				return err
+			}
+			if snooze.Until.After(at) {
+				snoozed = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("error reading snoozes: %v", err)
+	}
+
+	return snoozed, nil
+}
+
+func snoozeKey(repo, workflow string) []byte {
+	return []byte(repo + "\x00" + workflow)
+}
@@ -0,0 +1,82 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/store"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifications(t *testing.T) {
+	s, err := store.Open(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	now := time.Now()
+	require.NoError(t, s.MarkNotified("slack", notify.Notification{RunID: 2, Repo: "repo1", Workflow: "CI", StartedAt: now, AnnouncedAt: now}))
+	require.NoError(t, s.MarkNotified("slack", notify.Notification{RunID: 1, Repo: "repo1", Workflow: "Lint", StartedAt: now.Add(-time.Hour), AnnouncedAt: now}))
+
+	unresolved, err := s.Unresolved("slack")
+	require.NoError(t, err)
+	if assert.Len(t, unresolved, 2) {
+		assert.Equal(t, int64(1), unresolved[0].RunID)
+		assert.Equal(t, int64(2), unresolved[1].RunID)
+	}
+
+	require.NoError(t, s.MarkResolved("slack", 1, now))
+	assert.Error(t, s.MarkResolved("slack", 3, now))
+
+	unresolved, err = s.Unresolved("slack")
+	require.NoError(t, err)
+	if assert.Len(t, unresolved, 1) {
+		assert.Equal(t, int64(2), unresolved[0].RunID)
+	}
+
+	unresolved, err = s.Unresolved("webhook:pagerduty")
+	require.NoError(t, err)
+	assert.Empty(t, unresolved)
+}
+
+func TestSnoozes(t *testing.T) {
+	s, err := store.Open(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	now := time.Now()
+	require.NoError(t, s.SaveSnooze(store.Snooze{Repo: "repo1", Until: now.Add(time.Hour)}))
+	require.NoError(t, s.SaveSnooze(store.Snooze{Repo: "repo2", Workflow: "CI", Until: now.Add(time.Hour)}))
+	require.NoError(t, s.SaveSnooze(store.Snooze{Repo: "repo3", Until: now.Add(-time.Hour)}))
+
+	tests := []struct {
+		repo     string
+		workflow string
+		want     bool
+	}{
+		{"repo1", "CI", true},
+		{"repo1", "Lint", true},
+		{"repo2", "CI", true},
+		{"repo2", "Lint", false},
+		{"repo3", "CI", false},
+		{"repo4", "CI", false},
+	}
+	for _, tt := range tests {
+		snoozed, err := s.Snoozed(tt.repo, tt.workflow, now)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, snoozed, "%s/%s", tt.repo, tt.workflow)
+	}
+
+	snoozes, err := s.Snoozes(now)
+	require.NoError(t, err)
+	if assert.Len(t, snoozes, 2) {
+		assert.Equal(t, "repo1", snoozes[0].Repo)
+		assert.Equal(t, "CI", snoozes[1].Workflow)
+	}
+
+	require.NoError(t, s.DeleteSnooze("repo1", ""))
+	snoozed, err := s.Snoozed("repo1", "CI", now)
+	require.NoError(t, err)
+	assert.False(t, snoozed)
+}
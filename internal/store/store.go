@@ -0,0 +1,280 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	runsBucket          = []byte("runs")
+	watermarksBucket    = []byte("watermarks")
+	notificationsBucket = []byte("notifications")
+	snoozesBucket       = []byte("snoozes")
+)
+
+// Run is a single observed workflow run
+type Run struct {
+	ID         int64  `json:"id"`
+	Repo       string `json:"repo"`
+	Workflow   string `json:"workflow"`
+	Conclusion string `json:"conclusion"`
+	HeadSHA    string `json:"head_sha"`
+	Branch     string `json:"branch,omitempty"`
+	Actor      string `json:"actor,omitempty"`
+	PRNumber   int    `json:"pr_number,omitempty"`
+	PRURL      string `json:"pr_url,omitempty"`
+	URL        string `json:"url"`
+	// StartedAt is when the run was created
+	StartedAt time.Time `json:"started_at"`
+	// RunStartedAt is when the latest attempt of the run started executing
+	RunStartedAt time.Time `json:"run_started_at,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Failed reports whether the run counts as a failure
+func (r Run) Failed() bool {
+	switch r.Conclusion {
+	case "failure", "timed_out", "startup_failure":
+		return true
+	}
+	return false
+}
+
+// Duration returns how long the run took, or zero if unknown
+func (r Run) Duration() time.Duration {
+	start := r.RunStartedAt
+	if start.IsZero() {
+		start = r.StartedAt
+	}
+	if r.UpdatedAt.Before(start) {
+		return 0
+	}
+	return r.UpdatedAt.Sub(start)
+}
+
+// Watermark records the newest run seen for a repository
+type Watermark struct {
+	RunID       int64     `json:"run_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	CoveredFrom time.Time `json:"covered_from"`
+	SyncedAt    time.Time `json:"synced_at,omitempty"`
+	PendingFrom time.Time `json:"pending_from,omitempty"`
+}
+
+// Filter restricts which runs are returned from the store
+type Filter struct {
+	Repo     string
+	Workflow string
+	Since    time.Time
+	// FailedOnly restricts the result to failed runs
+	FailedOnly bool
+}
+
+// Store is the local history database
+type Store struct {
+	db *bolt.DB
+}
+
+// DefaultDir returns the default data directory, following the XDG base directory spec
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "gh-workflow-monitor"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding home directory: %v", err)
+	}
+	return filepath.Join(home, ".local", "share", "gh-workflow-monitor"), nil
+}
+
+// Open opens (creating if needed) the history database in the given directory
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating data directory: %v", err)
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, "history.db"), 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening history database: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{runsBucket, watermarksBucket, notificationsBucket, snoozesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error initializing history database: %v", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the history database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SaveRuns records runs in the store, returning how many were not seen before.
+// Runs that are already stored are overwritten with the latest observation.
+func (s *Store) SaveRuns(runs []Run) (int, error) {
+	added := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(runsBucket)
+		for _, run := range runs {
+			key := runKey(run.ID)
+			if bucket.Get(key) == nil {
+				added++
+			}
+
+			value, err := json.Marshal(run)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error saving runs: %v", err)
+	}
+
+	return added, nil
+}
+
+// Runs returns the stored runs matching the filter, most recent first
+func (s *Store) Runs(filter Filter) ([]Run, error) {
+	var runs []Run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(runsBucket).ForEach(func(_, value []byte) error {
+			var run Run
+			if err := json.Unmarshal(value, &run); err != nil {
+				return err
+			}
+
+			if filter.Repo != "" && run.Repo != filter.Repo {
+				return nil
+			}
+			if filter.Workflow != "" && run.Workflow != filter.Workflow {
+				return nil
+			}
+			if run.StartedAt.Before(filter.Since) {
+				return nil
+			}
+			if filter.FailedOnly && !run.Failed() {
+				return nil
+			}
+
+			runs = append(runs, run)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading runs: %v", err)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+
+	return runs, nil
+}
+
+// Watermarks returns the stored watermark of every repository
+func (s *Store) Watermarks() (map[string]Watermark, error) {
+	watermarks := make(map[string]Watermark)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(watermarksBucket).ForEach(func(key, value []byte) error {
+			var mark Watermark
+			if err := json.Unmarshal(value, &mark); err != nil {
+				return err
+			}
+			watermarks[string(key)] = mark
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading watermarks: %v", err)
+	}
+
+	return watermarks, nil
+}
+
+// SaveWatermarks records the watermarks of the given repositories
+func (s *Store) SaveWatermarks(watermarks map[string]Watermark) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(watermarksBucket)
+		for repo, mark := range watermarks {
+			value, err := json.Marshal(mark)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(repo), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error saving watermarks: %v", err)
+	}
+
+	return nil
+}
+
+// Notified reports whether the notifier has already handled the run
+func (s *Store) Notified(notifier string, runID int64) (bool, error) {
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(notificationsBucket).Get(notificationKey(notifier, runID)) != nil
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("error reading notifications: %v", err)
+	}
+
+	return found, nil
+}
+
+// MarkNotified records that the notifier has handled the failed run
+func (s *Store) MarkNotified(notifier string, n notify.Notification) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		value, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(notificationsBucket).Put(notificationKey(notifier, n.RunID), value)
+	})
+	if err != nil {
+		return fmt.Errorf("error saving notification: %v", err)
+	}
+
+	return nil
+}
+
+// notificationKey scopes a run key to a notifier so each notifier announces every run once
+func notificationKey(notifier string, runID int64) []byte {
+	return append([]byte(notifier+"\x00"), runKey(runID)...)
+}
+
+// runKey encodes a run ID so that keys sort numerically
+func runKey(id int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
@@ -0,0 +1,151 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/store"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveRuns(t *testing.T) {
+	s, err := store.Open(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	now := time.Now()
+	runs := []store.Run{
+		{ID: 1, Repo: "repo1", Workflow: "CI", Conclusion: "failure", StartedAt: now.Add(-2 * time.Hour)},
+		{ID: 2, Repo: "repo2", Workflow: "CI", Conclusion: "failure", StartedAt: now.Add(-time.Hour)},
+	}
+
+	added, err := s.SaveRuns(runs)
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	// Saving the same runs again only updates them
+	runs[0].Conclusion = "success"
+	added, err = s.SaveRuns(append(runs, store.Run{ID: 3, Repo: "repo1", Workflow: "Lint", StartedAt: now}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	stored, err := s.Runs(store.Filter{})
+	require.NoError(t, err)
+	if assert.Len(t, stored, 3) {
+		assert.Equal(t, int64(3), stored[0].ID)
+		assert.Equal(t, int64(2), stored[1].ID)
+		assert.Equal(t, "success", stored[2].Conclusion)
+	}
+}
+
+func TestRunsFilter(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.Open(dir)
+	require.NoError(t, err)
+
+	now := time.Now()
+	_, err = s.SaveRuns([]store.Run{
+		{ID: 1, Repo: "repo1", Workflow: "CI", Conclusion: "failure", StartedAt: now.AddDate(0, 0, -10)},
+		{ID: 2, Repo: "repo1", Workflow: "CI", Conclusion: "success", StartedAt: now},
+		{ID: 3, Repo: "repo1", Workflow: "Lint", Conclusion: "timed_out", StartedAt: now},
+		{ID: 4, Repo: "repo2", Workflow: "CI", Conclusion: "cancelled", StartedAt: now},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	// Runs persist across reopening the database
+	s, err = store.Open(dir)
+	require.NoError(t, err)
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		filter  store.Filter
+		wantIDs []int64
+	}{
+		{name: "no filter", filter: store.Filter{}, wantIDs: []int64{1, 2, 3, 4}},
+		{name: "by repo", filter: store.Filter{Repo: "repo2"}, wantIDs: []int64{4}},
+		{name: "by workflow", filter: store.Filter{Repo: "repo1", Workflow: "CI"}, wantIDs: []int64{1, 2}},
+		{name: "since", filter: store.Filter{Repo: "repo1", Workflow: "CI", Since: now.AddDate(0, 0, -1)}, wantIDs: []int64{2}},
+		{name: "failed only", filter: store.Filter{FailedOnly: true}, wantIDs: []int64{1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, err := s.Runs(tt.filter)
+			require.NoError(t, err)
+
+			var ids []int64
+			for _, run := range runs {
+				ids = append(ids, run.ID)
+			}
+			assert.ElementsMatch(t, tt.wantIDs, ids)
+		})
+	}
+}
+
+func TestWatermarks(t *testing.T) {
+	s, err := store.Open(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	marks, err := s.Watermarks()
+	require.NoError(t, err)
+	assert.Empty(t, marks)
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, s.SaveWatermarks(map[string]store.Watermark{
+		"repo1": {RunID: 10, CreatedAt: createdAt},
+	}))
+	require.NoError(t, s.SaveWatermarks(map[string]store.Watermark{
+		"repo2": {RunID: 20, CreatedAt: createdAt, SyncedAt: createdAt.Add(time.Hour)},
+	}))
+
+	marks, err = s.Watermarks()
+	require.NoError(t, err)
+	assert.Len(t, marks, 2)
+	assert.Equal(t, int64(10), marks["repo1"].RunID)
+	assert.True(t, createdAt.Equal(marks["repo2"].CreatedAt))
+	assert.True(t, createdAt.Add(time.Hour).Equal(marks["repo2"].SyncedAt))
+}
+
+func TestNotified(t *testing.T) {
+	s, err := store.Open(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	notified, err := s.Notified("slack", 1)
+	require.NoError(t, err)
+	assert.False(t, notified)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, s.MarkNotified("slack", notify.Notification{
+		RunID:       1,
+		Repo:        "repo1",
+		Workflow:    "CI",
+		Branch:      "main",
+		PRNumber:    7,
+		StartedAt:   now.Add(-time.Hour),
+		AnnouncedAt: now,
+	}))
+
+	notified, err = s.Notified("slack", 1)
+	require.NoError(t, err)
+	assert.True(t, notified)
+
+	// Notifiers track their announcements independently
+	notified, err = s.Notified("webhook:pagerduty", 1)
+	require.NoError(t, err)
+	assert.False(t, notified)
+
+	// The whole record is kept
+	unresolved, err := s.Unresolved("slack")
+	require.NoError(t, err)
+	if assert.Len(t, unresolved, 1) {
+		assert.Equal(t, "main", unresolved[0].Branch)
+		assert.Equal(t, 7, unresolved[0].PRNumber)
+		assert.True(t, now.Equal(unresolved[0].AnnouncedAt))
+	}
+}
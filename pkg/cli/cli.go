@@ -3,33 +3,181 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/config"
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/repo"
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/store"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/digest"
 	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/httpcache"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
 )
 
 // CLI represents the command-line interface
 type CLI struct {
+	Config       string        `help:"Configuration file (defaults to $XDG_CONFIG_HOME/gh-workflow-monitor/config.yaml)" env:"GWM_CONFIG" type:"path"`
+	Profile      string        `help:"Profile from the configuration file to use" env:"GWM_PROFILE"`
+	DataDir      string        `help:"Directory for the local history database (defaults to $XDG_DATA_HOME/gh-workflow-monitor)" env:"GWM_DATA_DIR"`
+	CacheDir     string        `help:"Directory for cached API responses (defaults to $XDG_CACHE_HOME/gh-workflow-monitor/http)" env:"GWM_CACHE_DIR"`
+	NoCache      bool          `help:"Do not cache API responses" env:"GWM_NO_CACHE"`
+	CacheMaxSize int           `help:"Evict the least recently used API responses beyond this many megabytes (0 for no limit)" env:"GWM_CACHE_MAX_SIZE" default:"100"`
+	CacheMaxAge  time.Duration `help:"Evict API responses that have not been used for this long (0 for no limit)" env:"GWM_CACHE_MAX_AGE" default:"168h"`
+	BaseURL      string        `help:"GitHub API URL, e.g. https://ghe.example.com/api/v3 for GitHub Enterprise Server" env:"GITHUB_API_URL"`
+
+	file *config.File `kong:"-"`
+	// cache is the response cache of the client, whose statistics are flushed on exit
+	cache *httpcache.Transport `kong:"-"`
+	// hostToken is the gh login of the host a repository argument switched the API to;
+	// it replaces the configured credentials, which belong to another host
+	hostToken string `kong:"-"`
+
 	List struct {
-		Days int `help:"Number of days to look back" default:"7"`
+		Days   int    `help:"Number of days to look back" default:"7"`
+		Full   bool   `help:"Rescan the whole window instead of only fetching runs newer than the last sync"`
+		Author string `help:"Only show PRs opened by this user or requesting their review (@me for yourself)"`
 	} `cmd:"" help:"List all failed workflow runs"`
 
+	Mine struct {
+		Days int  `help:"Number of days to look back" default:"7"`
+		Full bool `help:"Rescan the whole window instead of only fetching runs newer than the last sync"`
+	} `cmd:"" help:"List failed workflow runs on your PRs and PRs awaiting your review (list --author @me)"`
+
 	Check struct {
-		PR   string `help:"PR number to check" required:""`
-		Repo string `help:"Repository name" required:""`
+		URL     string `arg:"" optional:"" help:"Pull request URL, e.g. https://github.com/acme/api/pull/123"`
+		PR      string `help:"PR number to check (defaults to the open PR of the current git branch)"`
+		Repo    string `help:"Repository as NAME, OWNER/NAME or HOST/OWNER/NAME (defaults to GH_REPO or the origin remote of the current git checkout)"`
+		Comment bool   `help:"Create or update a failure summary comment on the PR"`
 	} `cmd:"" help:"Check workflow failures for a specific PR"`
+
+	TrackIssues struct {
+		Threshold int      `help:"Open an issue once a workflow has failed more than this many consecutive runs" default:"3"`
+		Label     []string `help:"Labels to apply to tracking issues" default:"ci-failure"`
+		DryRun    bool     `help:"Show what would be done without changing any issues"`
+	} `cmd:"" help:"Open, update or close tracking issues for workflows failing on the default branch"`
+
+	Branches struct {
+		Branch    []string `help:"Additional branches to report on wherever they exist"`
+		Protected bool     `help:"Also report on every protected branch"`
+		Culprit   bool     `help:"Also list the suspect commits of each failing workflow (one extra lookup per workflow)"`
+	} `cmd:"" help:"Report workflow failures on each repository's default branch"`
+
+	Culprit struct {
+		Repo     string `help:"Repository as NAME, OWNER/NAME or HOST/OWNER/NAME" required:""`
+		Workflow string `help:"Workflow name or file name" required:""`
+		Branch   string `help:"Branch to inspect (defaults to the repository's default branch)"`
+	} `cmd:"" help:"Identify the commits that broke a workflow"`
+
+	History struct {
+		Days     int    `help:"Number of days to look back" default:"30"`
+		Repo     string `help:"Only show runs for this repository"`
+		Workflow string `help:"Only show runs for this workflow"`
+		Failed   bool   `help:"Only show failed runs"`
+	} `cmd:"" help:"Show workflow runs recorded in the local history database (offline)"`
+
+	Stats struct {
+		Days   int    `help:"Number of days to look back" default:"30"`
+		By     string `help:"Group statistics by repository or workflow" enum:"repo,workflow" default:"workflow"`
+		Sort   string `help:"Sort rows by name, runs, failures, rate, duration, p95 or mttr" enum:"name,runs,failures,rate,duration,p95,mttr" default:"failures"`
+		Output string `help:"Output format: table, json or csv" enum:"table,json,csv" default:"table"`
+		Trend  bool   `help:"Chart failures per day across the window"`
+		ASCII  bool   `help:"Draw trend charts with ASCII characters only" name:"ascii"`
+	} `cmd:"" help:"Show failure statistics per repository or workflow"`
+
+	Serve struct {
+		Addr          string        `help:"Address to listen on" default:":9090"`
+		Metrics       bool          `help:"Expose Prometheus metrics on /metrics"`
+		Webhook       bool          `help:"Receive workflow_run and check_suite webhooks on /webhook"`
+		WebhookSecret string        `help:"Secret used to verify webhook signatures" env:"GITHUB_WEBHOOK_SECRET"`
+		Interval      time.Duration `help:"How often to poll GitHub" default:"5m"`
+		Days          int           `help:"Number of days of failed runs to count on each poll" default:"1"`
+		Notify        []string      `help:"Announce failures delivered to /webhook through these notifiers, configured as for the notify commands" enum:"slack,webhook"`
+	} `cmd:"" help:"Run as a long-lived service"`
+
+	Notify struct {
+		Slack struct {
+			WebhookURL string            `help:"Incoming webhook URL for repositories without a route" env:"SLACK_WEBHOOK_URL"`
+			Route      map[string]string `help:"Post failures of a repository to another incoming webhook (repo=url)"`
+			Days       int               `help:"Number of days to look back" default:"1"`
+		} `cmd:"" help:"Post new failures to Slack"`
+
+		Webhook struct {
+			URL         string            `help:"URL to post events to" env:"GWM_NOTIFY_URL" required:""`
+			Name        string            `help:"Name that identifies the webhook in the notification state (defaults to a hash of the URL)"`
+			Template    string            `help:"File with a Go text/template for the request body (defaults to a JSON payload)" type:"existingfile"`
+			ContentType string            `help:"Content type of the request body" default:"application/json"`
+			Header      map[string]string `help:"Header to add to every request (name=value)"`
+			Secret      string            `help:"Sign request bodies with HMAC-SHA256 in the X-Signature-256 header" env:"GWM_NOTIFY_SECRET"`
+			Retries     int               `help:"Number of times a failed delivery is retried" default:"3"`
+			Days        int               `help:"Number of days to look back" default:"1"`
+		} `cmd:"" help:"Post new failures to any webhook"`
+	} `cmd:"" help:"Announce new failures"`
+
+	Snooze struct {
+		Repo     string        `arg:"" optional:"" help:"Repository to snooze"`
+		Workflow string        `arg:"" optional:"" help:"Workflow to snooze (defaults to every workflow of the repository)"`
+		For      time.Duration `help:"How long to silence notifications" default:"24h"`
+		Remove   bool          `help:"Remove the snooze instead"`
+		List     bool          `help:"List the active snoozes"`
+	} `cmd:"" help:"Silence notifications for a repository or workflow"`
+
+	Digest struct {
+		Period       string            `help:"Period to summarize: daily or weekly" enum:"daily,weekly" default:"daily"`
+		To           []string          `help:"Send the digest of every repository to these addresses"`
+		Recipient    map[string]string `help:"Send a digest of only some repositories to an address (email=repo1,repo2)"`
+		SMTPHost     string            `help:"SMTP server host" name:"smtp-host" env:"GWM_SMTP_HOST"`
+		SMTPPort     int               `help:"SMTP server port" name:"smtp-port" env:"GWM_SMTP_PORT" default:"587"`
+		SMTPUsername string            `help:"SMTP username" name:"smtp-username" env:"GWM_SMTP_USERNAME"`
+		SMTPPassword string            `help:"SMTP password" name:"smtp-password" env:"GWM_SMTP_PASSWORD"`
+		From         string            `help:"Sender address" env:"GWM_SMTP_FROM"`
+		NoStartTLS   bool              `help:"Do not require STARTTLS, e.g. for a trusted local relay" name:"no-starttls"`
+		DryRun       bool              `help:"Print the digests instead of sending them"`
+	} `cmd:"" help:"Email a digest of failures"`
+
+	Doctor struct{} `cmd:"" help:"Check the configuration, token and permissions"`
+
+	Cache struct {
+		Stats struct{} `cmd:"" help:"Show API response cache statistics"`
+		Clear struct{} `cmd:"" help:"Remove all cached API responses"`
+	} `cmd:"" help:"Manage the API response cache"`
 }
 
-// HandleList handles the list command
-func HandleList(client github.Client, days int) error {
-	failures, err := client.ListAllFailedWorkflows(context.Background(), days)
+// HandleList handles the list command. When history is not nil, only runs newer than the stored
+// watermarks are fetched (unless full is set) and the report is built from the recorded history.
+func HandleList(client github.Client, history *store.Store, days int, full bool, author string) error {
+	var failures map[string][]github.WorkflowFailure
+	var err error
+	if history != nil {
+		failures, err = syncHistory(client, history, days, full)
+	} else {
+		failures, err = client.ListAllFailedWorkflows(context.Background(), days)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to list workflow failures: %w", err)
 	}
 
+	if author != "" {
+		if author == "@me" {
+			if author, err = client.CurrentUser(context.Background()); err != nil {
+				return fmt.Errorf("failed to resolve @me: %w", err)
+			}
+		}
+		failures = FilterInvolving(client, failures, author)
+	}
+
 	if len(failures) == 0 {
+		if author != "" {
+			fmt.Printf("No failed workflow runs found on PRs of %s in the last %d days\n", author, days)
+			return nil
+		}
 		fmt.Printf("No failed workflow runs found in the last %d days\n", days)
 		return nil
 	}
@@ -48,30 +196,847 @@ func HandleList(client github.Client, days int) error {
 	return nil
 }
 
+// FilterInvolving keeps the PRs that the user opened or whose review is requested from
+// them or from one of their teams. The author and reviewers are read from each PR, since
+// reviewers change after the failures were recorded. PRs that cannot be read are kept
+// with a warning rather than hidden, and teams whose membership cannot be checked are
+// treated as not including the user.
+func FilterInvolving(client github.Client, failures map[string][]github.WorkflowFailure, login string) map[string][]github.WorkflowFailure {
+	ctx := context.Background()
+	filtered := make(map[string][]github.WorkflowFailure)
+	var teams []string
+	checked := make(map[string]bool)
+
+	for prURL, prFailures := range failures {
+		if len(prFailures) == 0 {
+			continue
+		}
+
+		people, err := client.GetPullRequestPeople(ctx, prFailures[0].Repo, prFailures[0].PRNumber)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: showing %s unfiltered: %v\n", prURL, err)
+			filtered[prURL] = prFailures
+			continue
+		}
+
+		for _, team := range people.Teams {
+			if checked[team] {
+				continue
+			}
+			checked[team] = true
+			member, err := client.IsTeamMember(ctx, team, login)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to check membership of team %s: %v\n", team, err)
+				continue
+			}
+			if member {
+				teams = append(teams, team)
+			}
+		}
+
+		var kept []github.WorkflowFailure
+		for _, failure := range prFailures {
+			failure.SetPeople(people)
+			if failure.Involves(login, teams...) {
+				kept = append(kept, failure)
+			}
+		}
+		if len(kept) > 0 {
+			filtered[prURL] = kept
+		}
+	}
+
+	return filtered
+}
+
+// syncRuns fetches the completed runs newer than the stored watermarks (every run of the
+// window when full is set) into the history database
+func syncRuns(client github.Client, history *store.Store, days int, full bool) error {
+	var watermarks map[string]github.Watermark
+	if !full {
+		stored, err := history.Watermarks()
+		if err != nil {
+			return err
+		}
+
+		watermarks = make(map[string]github.Watermark, len(stored))
+		for repo, mark := range stored {
+			watermarks[repo] = github.Watermark{
+				RunID:       mark.RunID,
+				CreatedAt:   mark.CreatedAt,
+				CoveredFrom: mark.CoveredFrom,
+				SyncedAt:    mark.SyncedAt,
+				PendingFrom: mark.PendingFrom,
+			}
+		}
+	}
+
+	synced, updated, err := client.SyncRuns(context.Background(), days, watermarks)
+	if err != nil {
+		return err
+	}
+
+	runs := make([]store.Run, 0, len(synced))
+	for _, run := range synced {
+		runs = append(runs, store.Run{
+			ID:           run.ID,
+			Repo:         run.Repo,
+			Workflow:     run.Workflow,
+			Conclusion:   run.Conclusion,
+			HeadSHA:      run.HeadSHA,
+			Branch:       run.Branch,
+			Actor:        run.Actor,
+			PRNumber:     run.PRNumber,
+			PRURL:        run.PRURL,
+			URL:          run.URL,
+			StartedAt:    run.CreatedAt,
+			RunStartedAt: run.StartedAt,
+			UpdatedAt:    run.UpdatedAt,
+		})
+	}
+	if _, err := history.SaveRuns(runs); err != nil {
+		return err
+	}
+
+	marks := make(map[string]store.Watermark, len(updated))
+	for repo, mark := range updated {
+		marks[repo] = store.Watermark{
+			RunID:       mark.RunID,
+			CreatedAt:   mark.CreatedAt,
+			CoveredFrom: mark.CoveredFrom,
+			SyncedAt:    mark.SyncedAt,
+			PendingFrom: mark.PendingFrom,
+		}
+	}
+	return history.SaveWatermarks(marks)
+}
+
+// recordedRuns returns the runs recorded in the history database that match the filter
+func recordedRuns(history *store.Store, filter store.Filter) ([]github.RunSummary, error) {
+	recorded, err := history.Runs(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]github.RunSummary, 0, len(recorded))
+	for _, run := range recorded {
+		// Runs recorded by older versions only know when they were created
+		startedAt := run.RunStartedAt
+		if startedAt.IsZero() {
+			startedAt = run.StartedAt
+		}
+
+		runs = append(runs, github.RunSummary{
+			ID:         run.ID,
+			Repo:       run.Repo,
+			Branch:     run.Branch,
+			Workflow:   run.Workflow,
+			Conclusion: run.Conclusion,
+			HeadSHA:    run.HeadSHA,
+			Actor:      run.Actor,
+			PRNumber:   run.PRNumber,
+			PRURL:      run.PRURL,
+			CreatedAt:  run.StartedAt,
+			StartedAt:  startedAt,
+			UpdatedAt:  run.UpdatedAt,
+			URL:        run.URL,
+		})
+	}
+	return runs, nil
+}
+
+// syncHistory syncs the history database and returns all recorded PR failures within
+// the window, grouped by PR URL
+func syncHistory(client github.Client, history *store.Store, days int, full bool) (map[string][]github.WorkflowFailure, error) {
+	if err := syncRuns(client, history, days, full); err != nil {
+		return nil, err
+	}
+
+	recorded, err := recordedRuns(history, store.Filter{Since: time.Now().AddDate(0, 0, -days), FailedOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make(map[string][]github.WorkflowFailure)
+	for _, run := range recorded {
+		if run.PRURL == "" {
+			continue
+		}
+		failures[run.PRURL] = append(failures[run.PRURL], run.Failure())
+	}
+
+	return failures, nil
+}
+
+// HandleHistory handles the history command
+func HandleHistory(history *store.Store, filter store.Filter) error {
+	runs, err := history.Runs(filter)
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("No recorded workflow runs found")
+		return nil
+	}
+
+	fmt.Printf("Found %d recorded workflow runs:\n", len(runs))
+	fmt.Println("----------------------------------------")
+	for _, run := range runs {
+		fmt.Printf("%s  %s / %s  %s", run.StartedAt.Format(time.RFC3339), run.Repo, run.Workflow, run.Conclusion)
+		if run.PRNumber != 0 {
+			fmt.Printf("  PR #%d", run.PRNumber)
+		}
+		fmt.Printf("\n    %s\n", run.URL)
+	}
+
+	return nil
+}
+
+// openHistory opens the history database in dir, or in the default data directory when dir is empty
+func openHistory(dir string) (*store.Store, error) {
+	if dir == "" {
+		var err error
+		if dir, err = store.DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+	return store.Open(dir)
+}
+
 // HandleCheck handles the check command
-func HandleCheck(client github.Client, prNumber string, repo string) error {
+func HandleCheck(client github.Client, prNumber string, repo string, comment bool) error {
 	if repo == "" {
 		return fmt.Errorf("repository name is required")
 	}
 
-	err := client.GetFailedWorkflows(context.Background(), prNumber, repo)
+	failures, err := client.GetFailedWorkflows(context.Background(), prNumber, repo)
 	if err != nil {
 		return fmt.Errorf("failed to check workflow failures: %w", err)
 	}
 
+	if len(failures) == 0 {
+		fmt.Println("No failed workflow runs found for this PR.")
+	} else {
+		fmt.Printf("\nFailed workflow runs for PR #%s in %s:\n", prNumber, repo)
+		fmt.Println("----------------------------------------")
+		for _, failure := range failures {
+			fmt.Printf("Workflow: %s\n", failure.Workflow)
+			fmt.Printf("Started at: %s\n", failure.StartedAt.Format(time.RFC3339))
+			fmt.Printf("URL: %s\n", failure.URL)
+			for _, job := range failure.Jobs {
+				fmt.Printf("  - Job: %s (%s)\n", job.Name, job.URL)
+			}
+			fmt.Println("----------------------------------------")
+		}
+	}
+
+	if comment {
+		if err := client.CommentFailureSummary(context.Background(), prNumber, repo, failures); err != nil {
+			return fmt.Errorf("failed to comment on PR: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// FindBranchPullRequest returns the number of the open PR for a branch of the repository.
+// headOwner owns the repository the branch is pushed to, or is empty for the configured owner.
+func FindBranchPullRequest(client github.Client, repo, headOwner, branch string) (string, error) {
+	number, err := client.FindPullRequest(context.Background(), repo, headOwner, branch)
+	if err != nil {
+		return "", fmt.Errorf("failed to find PR for branch %s: %w", branch, err)
+	}
+
+	fmt.Printf("Checking PR #%d for branch %s\n", number, branch)
+	return strconv.Itoa(number), nil
+}
+
+// resolveCheck fills in the repository and PR of the check command from a PR URL or,
+// when no repository is given, from the current git checkout like gh does. It returns
+// the owner of the repository, or an empty string to use the configured owner.
+func (c *CLI) resolveCheck() (string, error) {
+	if c.Check.URL != "" {
+		if c.Check.PR != "" || c.Check.Repo != "" {
+			return "", fmt.Errorf("give either a PR URL or --pr and --repo, not both")
+		}
+		target, number, err := repo.ParsePullRequestURL(c.Check.URL)
+		if err != nil {
+			return "", err
+		}
+		if c.BaseURL, c.hostToken, err = ResolveRepo(target, c.BaseURL, config.GHToken); err != nil {
+			return "", err
+		}
+		c.Check.Repo, c.Check.PR = target.Name, strconv.Itoa(number)
+		return target.Owner, nil
+	}
+
+	if c.Check.Repo != "" {
+		var owner string
+		var err error
+		c.Check.Repo, owner, err = c.resolveRepoArg(c.Check.Repo)
+		return InferOwner(owner, config.Owner(c.file)), err
+	}
+
+	// The host of a remote may be an SSH alias, so only its owner and name are used
+	detected, err := repo.Detect()
+	if err != nil {
+		return "", fmt.Errorf("no repository given with --repo: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Using repository %s of the current checkout\n", detected.FullName())
+	c.Check.Repo = detected.Name
+	return detected.Owner, nil
+}
+
+// HandleTrackIssues handles the track-issues command
+func HandleTrackIssues(client github.Client, opts github.IssueOptions) error {
+	ctx := context.Background()
+
+	streaks, err := client.ListBranchStreaks(ctx, github.BranchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list default branch workflows: %w", err)
+	}
+
+	if opts.DryRun {
+		fmt.Println("Dry run: no issues will be changed")
+	}
+
+	actions, err := client.TrackFailureIssues(ctx, streaks, opts)
+
+	changed := 0
+	for _, action := range actions {
+		if action.Action == github.IssueActionNone {
+			continue
+		}
+
+		changed++
+		fmt.Printf("%s: %s (%s) on %s", action.Action, action.Workflow, action.Repo, action.Branch)
+		if action.IssueURL != "" {
+			fmt.Printf(" %s", action.IssueURL)
+		}
+		fmt.Println()
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to track issues: %w", err)
+	}
+	if changed == 0 {
+		fmt.Println("No tracking issues needed changes")
+	}
+
 	return nil
 }
 
+// HandleBranches handles the branches command.
+// The suspect commits of each failing workflow are only looked up when culprit is set.
+func HandleBranches(client github.Client, opts github.BranchOptions, culprit bool) error {
+	streaks, err := client.ListBranchStreaks(context.Background(), opts)
+	if err != nil {
+		return fmt.Errorf("failed to list branch workflows: %w", err)
+	}
+
+	var red []github.BranchHealth
+	for _, branch := range github.GroupBranches(streaks) {
+		if len(branch.Failing()) > 0 {
+			red = append(red, branch)
+		}
+	}
+
+	if len(red) == 0 {
+		fmt.Println("All inspected branches are green")
+		return nil
+	}
+
+	// Longest-red branches first
+	sort.SliceStable(red, func(i, j int) bool {
+		return red[i].RedSince().Before(red[j].RedSince())
+	})
+
+	fmt.Printf("Found %d branches with failing workflows:\n", len(red))
+	fmt.Println("----------------------------------------")
+	for _, branch := range red {
+		fmt.Printf("\n%s (%s): red for %s\n", branch.Repo, branch.Branch, time.Since(branch.RedSince()).Round(time.Minute))
+		for _, streak := range branch.Failing() {
+			first := streak.FirstFailure()
+			fmt.Printf("  - Workflow: %s\n", streak.Workflow)
+			fmt.Printf("    Failing since: %s (%d consecutive failed runs)\n", first.CreatedAt.Format(time.RFC3339), len(streak.FailingRuns))
+			fmt.Printf("    Latest failure: %s\n", streak.FailingRuns[0].URL)
+			if streak.LastSuccess != nil {
+				fmt.Printf("    Last green: %s (%s)\n", streak.LastSuccess.CreatedAt.Format(time.RFC3339), streak.LastSuccess.URL)
+			} else {
+				fmt.Printf("    Last green: none found\n")
+			}
+
+			if !culprit {
+				continue
+			}
+			suspects, err := client.FindCulprit(context.Background(), streak)
+			if err != nil {
+				fmt.Printf("    Suspect commits: unavailable (%v)\n", err)
+				continue
+			}
+			printCulpritCommits(suspects, "    ")
+		}
+		fmt.Println("----------------------------------------")
+	}
+
+	return nil
+}
+
+// HandleCulprit handles the culprit command
+func HandleCulprit(client github.Client, repo, branch, workflow string) error {
+	ctx := context.Background()
+
+	streak, err := client.GetWorkflowStreak(ctx, repo, branch, workflow)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow history: %w", err)
+	}
+
+	if !streak.Failing() {
+		fmt.Printf("Workflow %s is not failing on %s\n", streak.Workflow, streak.Branch)
+		return nil
+	}
+
+	culprit, err := client.FindCulprit(ctx, streak)
+	if err != nil {
+		return fmt.Errorf("failed to find culprit: %w", err)
+	}
+
+	fmt.Printf("Workflow %s on %s/%s\n", culprit.Workflow, culprit.Repo, culprit.Branch)
+	fmt.Println("----------------------------------------")
+	if culprit.LastGreen != nil {
+		fmt.Printf("Last green: run #%d at %s (%s)\n", culprit.LastGreen.Number, shortSHA(culprit.LastGreen.HeadSHA), culprit.LastGreen.URL)
+	} else {
+		fmt.Println("Last green: none found")
+	}
+	fmt.Printf("First red: run #%d at %s (%s)\n", culprit.FirstRed.Number, shortSHA(culprit.FirstRed.HeadSHA), culprit.FirstRed.URL)
+	printCulpritCommits(culprit, "")
+
+	return nil
+}
+
+// printCulpritCommits prints the suspect commits of a culprit
+func printCulpritCommits(culprit *github.Culprit, indent string) {
+	fmt.Printf("%sSuspect commits (%d):\n", indent, len(culprit.Commits))
+	for _, commit := range culprit.Commits {
+		fmt.Printf("%s  - %s %s: %s\n", indent, shortSHA(commit.SHA), commit.Author, commit.Message)
+	}
+	if culprit.CompareURL != "" {
+		fmt.Printf("%sCompare: %s\n", indent, culprit.CompareURL)
+	}
+}
+
+// shortSHA abbreviates a commit SHA
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// HandleCacheStats handles the cache stats command
+func HandleCacheStats(cache *httpcache.Transport) error {
+	stats, err := cache.Stats()
+	if err != nil {
+		return fmt.Errorf("failed to read cache statistics: %w", err)
+	}
+
+	fmt.Printf("Cache directory: %s\n", cache.Dir)
+	fmt.Printf("Entries: %d (%d bytes)\n", stats.Entries, stats.Bytes)
+	fmt.Printf("Hits (304 Not Modified): %d\n", stats.Hits)
+	fmt.Printf("Misses: %d\n", stats.Misses)
+	if total := stats.Hits + stats.Misses; total > 0 {
+		fmt.Printf("Hit rate: %.1f%%\n", float64(stats.Hits)/float64(total)*100)
+	}
+
+	return nil
+}
+
+// HandleCacheClear handles the cache clear command
+func HandleCacheClear(cache *httpcache.Transport) error {
+	if err := cache.Clear(); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+
+	fmt.Printf("Cleared cache in %s\n", cache.Dir)
+	return nil
+}
+
+// notifier builds the notifier of the notify command with the given name from its flags
+func (c *CLI) notifier(name string) (notify.Notifier, error) {
+	switch name {
+	case "slack":
+		if c.Notify.Slack.WebhookURL == "" && len(c.Notify.Slack.Route) == 0 {
+			return nil, fmt.Errorf("no Slack webhook configured: set --webhook-url, SLACK_WEBHOOK_URL or --route")
+		}
+		routes := make(map[string]string, len(c.Notify.Slack.Route))
+		for repo, url := range c.Notify.Slack.Route {
+			repo, err := c.repoName(repo)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --route: %w", err)
+			}
+			routes[repo] = url
+		}
+		return notify.NewSlack(c.Notify.Slack.WebhookURL, routes), nil
+	case "webhook":
+		opts := notify.WebhookOptions{
+			Name:        c.Notify.Webhook.Name,
+			ContentType: c.Notify.Webhook.ContentType,
+			Headers:     c.Notify.Webhook.Header,
+			Secret:      c.Notify.Webhook.Secret,
+			Retries:     c.Notify.Webhook.Retries,
+		}
+		if c.Notify.Webhook.Template != "" {
+			tmpl, err := os.ReadFile(c.Notify.Webhook.Template)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read template: %w", err)
+			}
+			opts.Template = string(tmpl)
+		}
+		return notify.NewWebhook(c.Notify.Webhook.URL, opts)
+	}
+	return nil, fmt.Errorf("unknown notifier: %s", name)
+}
+
+// configuredNotifier builds the notifier that `notify <name>` would use, resolving its
+// flags from the environment and the configuration file
+func (c *CLI) configuredNotifier(name string) (notify.Notifier, error) {
+	var args []string
+	if c.Config != "" {
+		args = append(args, "--config", c.Config)
+	}
+	if c.Profile != "" {
+		args = append(args, "--profile", c.Profile)
+	}
+	if c.BaseURL != "" {
+		args = append(args, "--base-url", c.BaseURL)
+	}
+
+	configured, _, err := Parse(append(args, "notify", name))
+	if err != nil {
+		return nil, fmt.Errorf("notifier %s is not configured: %w", name, err)
+	}
+	return configured.notifier(name)
+}
+
+// newCache creates the response cache in dir with the configured limits
+func (c *CLI) newCache(dir string) *httpcache.Transport {
+	cache := httpcache.New(dir, nil)
+	cache.MaxBytes = int64(c.CacheMaxSize) << 20
+	cache.MaxAge = c.CacheMaxAge
+	return cache
+}
+
+// flushCache saves the hit and miss counts of the response cache, if one was used
+func (c *CLI) flushCache() {
+	if c.cache == nil {
+		return
+	}
+	if err := c.cache.Flush(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+// cacheDir returns the configured cache directory, falling back to the default
+func (c *CLI) cacheDir() (string, error) {
+	if c.CacheDir != "" {
+		return c.CacheDir, nil
+	}
+	return httpcache.DefaultDir()
+}
+
+// newClient loads the configuration and creates a GitHub client. A non-empty owner
+// overrides the configured one.
+func (c *CLI) newClient(owner string) (github.Client, error) {
+	cfg, err := config.Load(c.file, c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if owner != "" {
+		cfg.GitHubOwner = owner
+	}
+	if c.hostToken != "" {
+		cfg.GitHubToken, cfg.RefreshToken, cfg.App = c.hostToken, nil, nil
+	}
+	if cfg.GitHubOwner == "" {
+		return nil, fmt.Errorf("failed to load configuration: GITHUB_OWNER environment variable or owner setting is required")
+	}
+
+	opts := []github.Option{
+		github.WithRepoFilter(cfg.Repos, cfg.ExcludeRepos),
+		github.WithBaseURL(c.BaseURL),
+	}
+	if cfg.RefreshToken != nil {
+		opts = append(opts, github.WithTokenRefresh(cfg.RefreshToken))
+	}
+	if cfg.App != nil {
+		key, err := github.ParsePrivateKey([]byte(cfg.App.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load app private key: %w", err)
+		}
+		opts = append(opts, github.WithApp(github.AppConfig{
+			AppID:          cfg.App.ID,
+			InstallationID: cfg.App.InstallationID,
+			PrivateKey:     key,
+		}))
+	}
+	if !c.NoCache {
+		dir, err := c.cacheDir()
+		if err != nil {
+			return nil, err
+		}
+		c.cache = c.newCache(dir)
+		opts = append(opts, github.WithCache(c.cache))
+	}
+
+	return github.NewClient(cfg.GitHubToken, cfg.GitHubOwner, opts...)
+}
+
+// Parse parses command-line arguments. Flags that are not given fall back to their
+// environment variable and then to the configuration file.
+func Parse(args []string, options ...kong.Option) (*CLI, *kong.Context, error) {
+	cli := &CLI{}
+	resolver := &configResolver{}
+
+	parser, err := kong.New(cli, append([]kong.Option{kong.Resolvers(resolver)}, options...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, err := parser.Parse(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The resolver only loads the file when a flag needs resolving
+	if err := resolver.load(ctx); err != nil {
+		return nil, nil, err
+	}
+	cli.file = resolver.File()
+
+	// Profiles keep separate history so that watermarks and notifications of different
+	// organizations never mix
+	if cli.Profile != "" && cli.DataDir == "" {
+		dir, err := store.DefaultDir()
+		if err != nil {
+			return nil, nil, err
+		}
+		cli.DataDir = filepath.Join(dir, "profiles", cli.Profile)
+	}
+
+	return cli, ctx, nil
+}
+
 // Run executes the CLI application
-func Run(client github.Client) error {
-	var cli CLI
-	ctx := kong.Parse(&cli)
+func Run() error {
+	if err := config.LoadDotEnv(); err != nil {
+		return err
+	}
+
+	cli, ctx, err := Parse(os.Args[1:])
+	if err != nil {
+		return err
+	}
+
+	// Commands that work offline
+	switch ctx.Command() {
+	case "history":
+		if cli.History.Repo != "" {
+			if cli.History.Repo, err = cli.repoName(cli.History.Repo); err != nil {
+				return err
+			}
+		}
+		history, err := openHistory(cli.DataDir)
+		if err != nil {
+			return err
+		}
+		defer history.Close()
+		return HandleHistory(history, store.Filter{
+			Repo:       cli.History.Repo,
+			Workflow:   cli.History.Workflow,
+			Since:      time.Now().AddDate(0, 0, -cli.History.Days),
+			FailedOnly: cli.History.Failed,
+		})
+	case "snooze", "snooze <repo>", "snooze <repo> <workflow>":
+		if cli.Snooze.Repo != "" {
+			if cli.Snooze.Repo, err = cli.repoName(cli.Snooze.Repo); err != nil {
+				return err
+			}
+		}
+		history, err := openHistory(cli.DataDir)
+		if err != nil {
+			return err
+		}
+		defer history.Close()
+		if cli.Snooze.List {
+			return HandleSnoozeList(history)
+		}
+		duration := cli.Snooze.For
+		if cli.Snooze.Remove {
+			duration = 0
+		}
+		return HandleSnooze(history, cli.Snooze.Repo, cli.Snooze.Workflow, duration)
+	case "cache stats", "cache clear":
+		dir, err := cli.cacheDir()
+		if err != nil {
+			return err
+		}
+		cache := cli.newCache(dir)
+		if ctx.Command() == "cache stats" {
+			return HandleCacheStats(cache)
+		}
+		return HandleCacheClear(cache)
+	}
+
+	defer cli.flushCache()
+
+	if ctx.Command() == "doctor" {
+		return HandleDoctor(func() (github.Client, error) { return cli.newClient("") })
+	}
 
+	owner := ""
 	switch ctx.Command() {
-	case "list":
-		return HandleList(client, cli.List.Days)
-	case "check":
-		return HandleCheck(client, cli.Check.PR, cli.Check.Repo)
+	case "check", "check <url>":
+		if owner, err = cli.resolveCheck(); err != nil {
+			return err
+		}
+	case "culprit":
+		if cli.Culprit.Repo, owner, err = cli.resolveRepoArg(cli.Culprit.Repo); err != nil {
+			return err
+		}
+		owner = InferOwner(owner, config.Owner(cli.file))
+	case "digest":
+		for addr, repos := range cli.Digest.Recipient {
+			var names []string
+			for _, name := range strings.Split(repos, ",") {
+				if name = strings.TrimSpace(name); name == "" {
+					continue
+				}
+				if name, err = cli.repoName(name); err != nil {
+					return fmt.Errorf("invalid --recipient: %w", err)
+				}
+				names = append(names, name)
+			}
+			cli.Digest.Recipient[addr] = strings.Join(names, ",")
+		}
+	}
+
+	client, err := cli.newClient(owner)
+	if err != nil {
+		return err
+	}
+
+	switch ctx.Command() {
+	case "list", "mine":
+		days, full, author := cli.List.Days, cli.List.Full, cli.List.Author
+		if ctx.Command() == "mine" {
+			days, full, author = cli.Mine.Days, cli.Mine.Full, "@me"
+		}
+		history, err := openHistory(cli.DataDir)
+		if err != nil {
+			fmt.Printf("Warning: history will not be recorded: %v\n", err)
+			return HandleList(client, nil, days, full, author)
+		}
+		defer history.Close()
+		return HandleList(client, history, days, full, author)
+	case "check", "check <url>":
+		if cli.Check.PR == "" {
+			branch, err := repo.CurrentBranch()
+			if err != nil {
+				return fmt.Errorf("no PR given with --pr: %w", err)
+			}
+			// Branches pushed to a fork are the head of a pull request from the fork's owner
+			var headOwner string
+			if remote, err := repo.PushRemote(branch); err == nil {
+				headOwner = remote.Owner
+			}
+			if cli.Check.PR, err = FindBranchPullRequest(client, cli.Check.Repo, headOwner, branch); err != nil {
+				return err
+			}
+		}
+		return HandleCheck(client, cli.Check.PR, cli.Check.Repo, cli.Check.Comment)
+	case "track-issues":
+		return HandleTrackIssues(client, github.IssueOptions{
+			Threshold: cli.TrackIssues.Threshold,
+			Labels:    cli.TrackIssues.Label,
+			DryRun:    cli.TrackIssues.DryRun,
+		})
+	case "branches":
+		return HandleBranches(client, github.BranchOptions{
+			Branches:  cli.Branches.Branch,
+			Protected: cli.Branches.Protected,
+		}, cli.Branches.Culprit)
+	case "culprit":
+		return HandleCulprit(client, cli.Culprit.Repo, cli.Culprit.Branch, cli.Culprit.Workflow)
+	case "serve":
+		opts := ServeOptions{
+			Addr:          cli.Serve.Addr,
+			Metrics:       cli.Serve.Metrics,
+			Webhook:       cli.Serve.Webhook,
+			WebhookSecret: cli.Serve.WebhookSecret,
+			Interval:      cli.Serve.Interval,
+			Days:          cli.Serve.Days,
+		}
+		if len(cli.Serve.Notify) > 0 {
+			for _, name := range cli.Serve.Notify {
+				notifier, err := cli.configuredNotifier(name)
+				if err != nil {
+					return err
+				}
+				opts.Notifiers = append(opts.Notifiers, notifier)
+			}
+			history, err := openHistory(cli.DataDir)
+			if err != nil {
+				return err
+			}
+			defer history.Close()
+			opts.State = history
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return HandleServe(ctx, client, opts)
+	case "notify slack", "notify webhook":
+		name := strings.TrimPrefix(ctx.Command(), "notify ")
+		notifier, err := cli.notifier(name)
+		if err != nil {
+			return err
+		}
+		history, err := openHistory(cli.DataDir)
+		if err != nil {
+			return err
+		}
+		defer history.Close()
+		days := cli.Notify.Slack.Days
+		if name == "webhook" {
+			days = cli.Notify.Webhook.Days
+		}
+		return HandleNotify(client, history, notifier, days)
+	case "digest":
+		days := 1
+		if cli.Digest.Period == "weekly" {
+			days = 7
+		}
+		return HandleDigest(client, DigestOptions{
+			Days:       days,
+			Recipients: ParseRecipients(cli.Digest.To, cli.Digest.Recipient),
+			SMTP: digest.SMTPConfig{
+				Host:     cli.Digest.SMTPHost,
+				Port:     cli.Digest.SMTPPort,
+				Username: cli.Digest.SMTPUsername,
+				Password: cli.Digest.SMTPPassword,
+				From:     cli.Digest.From,
+				StartTLS: !cli.Digest.NoStartTLS,
+			},
+			DryRun: cli.Digest.DryRun,
+		})
+	case "stats":
+		history, err := openHistory(cli.DataDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: history will not be used: %v\n", err)
+		} else {
+			defer history.Close()
+		}
+		return HandleStats(client, history, cli.Stats.Days, cli.Stats.By, cli.Stats.Sort, cli.Stats.Output, cli.Stats.Trend, cli.Stats.ASCII)
 	default:
 		return fmt.Errorf("unknown command: %s", ctx.Command())
 	}
@@ -114,5 +1079,6 @@ func handleList(client github.Client, days int) error {
 
 func handleCheck(client github.Client, repo, pr string) error {
 	ctx := context.Background()
-	return client.GetFailedWorkflows(ctx, pr, repo)
+	_, err := client.GetFailedWorkflows(ctx, pr, repo)
+	return err
 }
@@ -3,12 +3,15 @@ package cli_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/store"
 	"github.com/kjkondratuk/gh-workflow-monitor/pkg/cli"
 	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
 	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandleList(t *testing.T) {
@@ -49,7 +52,7 @@ func TestHandleList(t *testing.T) {
 			mockClient := mocks.NewMockClient(t)
 			tt.setupMock(mockClient)
 
-			err := cli.HandleList(mockClient, tt.days)
+			err := cli.HandleList(mockClient, nil, tt.days, false, "")
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -61,18 +64,140 @@ func TestHandleList(t *testing.T) {
 	}
 }
 
+func TestHandleListAuthor(t *testing.T) {
+	failures := map[string][]github.WorkflowFailure{
+		"https://github.com/acme/api/pull/1": {{Repo: "api", PRNumber: 1}},
+	}
+
+	mockClient := mocks.NewMockClient(t)
+	mockClient.EXPECT().ListAllFailedWorkflows(mock.Anything, 7).Return(failures, nil)
+	mockClient.EXPECT().CurrentUser(mock.Anything).Return("monalisa", nil)
+	mockClient.EXPECT().GetPullRequestPeople(mock.Anything, "api", 1).Return(github.PullRequestPeople{Author: "monalisa"}, nil)
+	assert.NoError(t, cli.HandleList(mockClient, nil, 7, false, "@me"))
+
+	mockClient = mocks.NewMockClient(t)
+	mockClient.EXPECT().ListAllFailedWorkflows(mock.Anything, 7).Return(failures, nil)
+	mockClient.EXPECT().CurrentUser(mock.Anything).Return("", fmt.Errorf("resource not accessible by integration"))
+	assert.Error(t, cli.HandleList(mockClient, nil, 7, false, "@me"))
+}
+
+func TestFilterInvolving(t *testing.T) {
+	pr := func(n int) string { return fmt.Sprintf("https://github.com/acme/api/pull/%d", n) }
+	failures := map[string][]github.WorkflowFailure{
+		pr(1): {{Repo: "api", PRNumber: 1, Workflow: "CI"}, {Repo: "api", PRNumber: 1, Workflow: "Lint"}},
+		pr(2): {{Repo: "api", PRNumber: 2}},
+		pr(3): {{Repo: "api", PRNumber: 3}},
+		pr(4): {{Repo: "api", PRNumber: 4}},
+		pr(5): {{Repo: "api", PRNumber: 5}},
+		pr(6): {{Repo: "api", PRNumber: 6}},
+		pr(7): {{Repo: "api", PRNumber: 7}},
+	}
+
+	mockClient := mocks.NewMockClient(t)
+	mockClient.EXPECT().GetPullRequestPeople(mock.Anything, "api", 1).Return(github.PullRequestPeople{Author: "monalisa"}, nil)
+	mockClient.EXPECT().GetPullRequestPeople(mock.Anything, "api", 2).Return(github.PullRequestPeople{Author: "hubot", Reviewers: []string{"MonaLisa"}}, nil)
+	mockClient.EXPECT().GetPullRequestPeople(mock.Anything, "api", 3).Return(github.PullRequestPeople{Author: "hubot", Teams: []string{"backend"}}, nil)
+	mockClient.EXPECT().GetPullRequestPeople(mock.Anything, "api", 4).Return(github.PullRequestPeople{Author: "hubot", Teams: []string{"frontend"}}, nil)
+	mockClient.EXPECT().GetPullRequestPeople(mock.Anything, "api", 5).Return(github.PullRequestPeople{Author: "hubot"}, nil)
+	mockClient.EXPECT().GetPullRequestPeople(mock.Anything, "api", 6).Return(github.PullRequestPeople{}, fmt.Errorf("403 rate limit exceeded"))
+	mockClient.EXPECT().GetPullRequestPeople(mock.Anything, "api", 7).Return(github.PullRequestPeople{Author: "hubot", Teams: []string{"secret"}}, nil)
+	mockClient.EXPECT().IsTeamMember(mock.Anything, "backend", "monalisa").Return(true, nil).Once()
+	mockClient.EXPECT().IsTeamMember(mock.Anything, "frontend", "monalisa").Return(false, nil).Once()
+	mockClient.EXPECT().IsTeamMember(mock.Anything, "secret", "monalisa").Return(false, fmt.Errorf("404 Not Found")).Once()
+
+	filtered := cli.FilterInvolving(mockClient, failures, "monalisa")
+
+	kept := make([]string, 0, len(filtered))
+	for prURL := range filtered {
+		kept = append(kept, prURL)
+	}
+	// PRs the user opened, or whose review is requested from them or their team, and
+	// PRs that could not be read; a team whose membership could not be checked does
+	// not count
+	assert.ElementsMatch(t, []string{pr(1), pr(2), pr(3), pr(6)}, kept)
+	assert.Len(t, filtered[pr(1)], 2)
+	assert.Equal(t, "monalisa", filtered[pr(1)][1].PRAuthor)
+	assert.Equal(t, []string{"MonaLisa"}, filtered[pr(2)][0].Reviewers)
+}
+
+func TestHandleListRecordsHistory(t *testing.T) {
+	history, err := store.Open(t.TempDir())
+	require.NoError(t, err)
+	defer history.Close()
+
+	firstSeen := time.Now().Add(-time.Hour)
+	watermark := github.Watermark{RunID: 123, CreatedAt: firstSeen, CoveredFrom: time.Now().AddDate(0, 0, -7)}
+
+	mockClient := mocks.NewMockClient(t)
+	mockClient.EXPECT().SyncRuns(mock.Anything, 7, map[string]github.Watermark{}).Return([]github.RunSummary{
+		{ID: 123, Repo: "repo1", PRNumber: 1, Workflow: "CI", Conclusion: "failure", CreatedAt: firstSeen, PRURL: "https://github.com/owner/repo1/pull/1"},
+		{ID: 122, Repo: "repo1", Branch: "main", Workflow: "CI", Conclusion: "success", CreatedAt: firstSeen.Add(-time.Minute)},
+	}, map[string]github.Watermark{"repo1": watermark}, nil).Once()
+
+	require.NoError(t, cli.HandleList(mockClient, history, 7, false, ""))
+
+	// Every completed run is recorded, not only failures on PRs
+	runs, err := history.Runs(store.Filter{})
+	require.NoError(t, err)
+	if assert.Len(t, runs, 2) {
+		assert.Equal(t, int64(123), runs[0].ID)
+		assert.Equal(t, "repo1", runs[0].Repo)
+		assert.Equal(t, 1, runs[0].PRNumber)
+		assert.Equal(t, "success", runs[1].Conclusion)
+		assert.Equal(t, "main", runs[1].Branch)
+	}
+
+	// The next sync resumes from the stored watermark
+	mockClient.EXPECT().SyncRuns(mock.Anything, 7, mock.MatchedBy(func(marks map[string]github.Watermark) bool {
+		return marks["repo1"].RunID == 123 && marks["repo1"].CreatedAt.Equal(firstSeen)
+	})).Return(nil, map[string]github.Watermark{"repo1": watermark}, nil).Once()
+
+	require.NoError(t, cli.HandleList(mockClient, history, 7, false, ""))
+
+	// A full sync ignores the watermarks
+	mockClient.EXPECT().SyncRuns(mock.Anything, 7, map[string]github.Watermark(nil)).Return(nil, nil, nil).Once()
+
+	require.NoError(t, cli.HandleList(mockClient, history, 7, true, ""))
+
+	assert.NoError(t, cli.HandleHistory(history, store.Filter{Repo: "repo1"}))
+}
+
 func TestHandleCheck(t *testing.T) {
+	failures := []github.WorkflowFailure{
+		{
+			Repo:      "test-repo",
+			PRNumber:  123,
+			Workflow:  "workflow1",
+			StartedAt: time.Now(),
+			URL:       "https://github.com/owner/test-repo/actions/runs/1",
+			PRURL:     "https://github.com/owner/test-repo/pull/123",
+			Jobs: []github.JobFailure{
+				{Name: "build", URL: "https://github.com/owner/test-repo/actions/runs/1/job/2"},
+			},
+		},
+	}
+
 	tests := []struct {
 		name      string
 		setupMock func(*mocks.MockClient)
 		prNumber  string
 		repo      string
+		comment   bool
 		wantErr   bool
 	}{
 		{
 			name: "successful check",
 			setupMock: func(m *mocks.MockClient) {
-				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(nil)
+				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(nil, nil)
+			},
+			prNumber: "123",
+			repo:     "test-repo",
+			wantErr:  false,
+		},
+		{
+			name: "check with failures",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(failures, nil)
 			},
 			prNumber: "123",
 			repo:     "test-repo",
@@ -81,12 +206,283 @@ func TestHandleCheck(t *testing.T) {
 		{
 			name: "error from client",
 			setupMock: func(m *mocks.MockClient) {
-				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(fmt.Errorf("mock error"))
+				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(nil, fmt.Errorf("mock error"))
 			},
 			prNumber: "123",
 			repo:     "test-repo",
 			wantErr:  true,
 		},
+		{
+			name: "check with comment",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(failures, nil)
+				m.EXPECT().CommentFailureSummary(mock.Anything, "123", "test-repo", failures).Return(nil)
+			},
+			prNumber: "123",
+			repo:     "test-repo",
+			comment:  true,
+			wantErr:  false,
+		},
+		{
+			name: "comment error",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(failures, nil)
+				m.EXPECT().CommentFailureSummary(mock.Anything, "123", "test-repo", failures).Return(fmt.Errorf("mock error"))
+			},
+			prNumber: "123",
+			repo:     "test-repo",
+			comment:  true,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := mocks.NewMockClient(t)
+			tt.setupMock(mockClient)
+
+			err := cli.HandleCheck(mockClient, tt.prNumber, tt.repo, tt.comment)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestFindBranchPullRequest(t *testing.T) {
+	m := mocks.NewMockClient(t)
+	m.EXPECT().FindPullRequest(mock.Anything, "api", "octocat", "feature").Return(42, nil)
+	m.EXPECT().FindPullRequest(mock.Anything, "api", "", "stale").Return(0, fmt.Errorf("no open pull request found"))
+
+	pr, err := cli.FindBranchPullRequest(m, "api", "octocat", "feature")
+	require.NoError(t, err)
+	assert.Equal(t, "42", pr)
+
+	_, err = cli.FindBranchPullRequest(m, "api", "", "stale")
+	assert.Error(t, err)
+}
+
+func TestParseCheckURL(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	c, ctx, err := cli.Parse([]string{"check", "https://github.com/acme/api/pull/123", "--comment"})
+	require.NoError(t, err)
+	assert.Equal(t, "check <url>", ctx.Command())
+	assert.Equal(t, "https://github.com/acme/api/pull/123", c.Check.URL)
+	assert.True(t, c.Check.Comment)
+
+	_, ctx, err = cli.Parse([]string{"check"})
+	require.NoError(t, err)
+	assert.Equal(t, "check", ctx.Command())
+}
+
+func TestHandleTrackIssues(t *testing.T) {
+	streak := github.WorkflowStreak{
+		Repo:     "test-repo",
+		Branch:   "main",
+		Workflow: "CI",
+		FailingRuns: []github.RunSummary{
+			{ID: 1, Conclusion: "failure"},
+		},
+	}
+	opts := github.IssueOptions{Threshold: 0, Labels: []string{"ci-failure"}, DryRun: true}
+
+	tests := []struct {
+		name      string
+		setupMock func(*mocks.MockClient)
+		wantErr   bool
+	}{
+		{
+			name: "opens issue",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().ListBranchStreaks(mock.Anything, github.BranchOptions{}).Return([]github.WorkflowStreak{streak}, nil)
+				m.EXPECT().TrackFailureIssues(mock.Anything, []github.WorkflowStreak{streak}, opts).Return([]github.IssueAction{{
+					Repo:     "test-repo",
+					Branch:   "main",
+					Workflow: "CI",
+					Action:   github.IssueActionOpen,
+				}}, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "error listing streaks",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().ListBranchStreaks(mock.Anything, github.BranchOptions{}).Return(nil, fmt.Errorf("mock error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "error tracking issue",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().ListBranchStreaks(mock.Anything, github.BranchOptions{}).Return([]github.WorkflowStreak{streak}, nil)
+				m.EXPECT().TrackFailureIssues(mock.Anything, []github.WorkflowStreak{streak}, opts).Return(nil, fmt.Errorf("mock error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := mocks.NewMockClient(t)
+			tt.setupMock(mockClient)
+
+			err := cli.HandleTrackIssues(mockClient, opts)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHandleBranches(t *testing.T) {
+	opts := github.BranchOptions{Branches: []string{"release"}}
+	streaks := []github.WorkflowStreak{
+		{
+			Repo:     "test-repo",
+			Branch:   "main",
+			Workflow: "CI",
+			FailingRuns: []github.RunSummary{
+				{ID: 2, Conclusion: "failure", CreatedAt: time.Now().Add(-time.Hour)},
+			},
+			LastSuccess: &github.RunSummary{ID: 1, Conclusion: "success", CreatedAt: time.Now().Add(-2 * time.Hour)},
+		},
+		{
+			Repo:     "test-repo",
+			Branch:   "release",
+			Workflow: "CI",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		setupMock func(*mocks.MockClient)
+		culprit   bool
+		wantErr   bool
+	}{
+		{
+			name: "red branches",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().ListBranchStreaks(mock.Anything, opts).Return(streaks, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "red branches with culprits",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().ListBranchStreaks(mock.Anything, opts).Return(streaks, nil)
+				m.EXPECT().FindCulprit(mock.Anything, streaks[0]).Return(&github.Culprit{
+					Commits: []github.CommitInfo{{SHA: "abcdef123456", Author: "octocat", Message: "Break things"}},
+				}, nil)
+			},
+			culprit: true,
+			wantErr: false,
+		},
+		{
+			name: "culprit unavailable",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().ListBranchStreaks(mock.Anything, opts).Return(streaks, nil)
+				m.EXPECT().FindCulprit(mock.Anything, streaks[0]).Return(nil, fmt.Errorf("mock error"))
+			},
+			culprit: true,
+			wantErr: false,
+		},
+		{
+			name: "all green",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().ListBranchStreaks(mock.Anything, opts).Return(nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "error from client",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().ListBranchStreaks(mock.Anything, opts).Return(nil, fmt.Errorf("mock error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := mocks.NewMockClient(t)
+			tt.setupMock(mockClient)
+
+			err := cli.HandleBranches(mockClient, opts, tt.culprit)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHandleCulprit(t *testing.T) {
+	failing := github.WorkflowStreak{
+		Repo:        "test-repo",
+		Branch:      "main",
+		Workflow:    "CI",
+		FailingRuns: []github.RunSummary{{ID: 2, Number: 2, Conclusion: "failure", HeadSHA: "bbbbbbbb"}},
+		LastSuccess: &github.RunSummary{ID: 1, Number: 1, Conclusion: "success", HeadSHA: "aaaaaaaa"},
+	}
+	culprit := &github.Culprit{
+		Repo:      "test-repo",
+		Branch:    "main",
+		Workflow:  "CI",
+		LastGreen: failing.LastSuccess,
+		FirstRed:  failing.FailingRuns[0],
+		Commits: []github.CommitInfo{
+			{SHA: "bbbbbbbb", Author: "octocat", Message: "Break things"},
+		},
+		CompareURL: "https://github.com/owner/test-repo/compare/aaaaaaaa...bbbbbbbb",
+	}
+
+	tests := []struct {
+		name      string
+		setupMock func(*mocks.MockClient)
+		wantErr   bool
+	}{
+		{
+			name: "failing workflow",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().GetWorkflowStreak(mock.Anything, "test-repo", "", "CI").Return(failing, nil)
+				m.EXPECT().FindCulprit(mock.Anything, failing).Return(culprit, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "passing workflow",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().GetWorkflowStreak(mock.Anything, "test-repo", "", "CI").Return(github.WorkflowStreak{Repo: "test-repo", Workflow: "CI"}, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "error getting history",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().GetWorkflowStreak(mock.Anything, "test-repo", "", "CI").Return(github.WorkflowStreak{}, fmt.Errorf("mock error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "error finding culprit",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().GetWorkflowStreak(mock.Anything, "test-repo", "", "CI").Return(failing, nil)
+				m.EXPECT().FindCulprit(mock.Anything, failing).Return(nil, fmt.Errorf("mock error"))
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -94,7 +490,7 @@ func TestHandleCheck(t *testing.T) {
 			mockClient := mocks.NewMockClient(t)
 			tt.setupMock(mockClient)
 
-			err := cli.HandleCheck(mockClient, tt.prNumber, tt.repo)
+			err := cli.HandleCulprit(mockClient, "test-repo", "", "CI")
 
 			if tt.wantErr {
 				assert.Error(t, err)
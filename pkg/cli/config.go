@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/alecthomas/kong"
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/config"
+)
+
+// configResolver fills in flags that were not given on the command line from the
+// configuration file. Flags whose environment variable is set are left alone, so
+// precedence is flags > env > file.
+type configResolver struct {
+	file *config.File
+	err  error
+}
+
+var _ kong.Resolver = (*configResolver)(nil)
+
+// Validate implements kong.Resolver
+func (r *configResolver) Validate(*kong.Application) error {
+	return nil
+}
+
+// Resolve implements kong.Resolver
+func (r *configResolver) Resolve(ctx *kong.Context, parent *kong.Path, flag *kong.Flag) (any, error) {
+	if flag.Name == "config" || flag.Name == "profile" {
+		return nil, nil
+	}
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, env := range flag.Envs {
+		if _, ok := os.LookupEnv(env); ok {
+			return nil, nil
+		}
+	}
+
+	value, ok := r.file.Lookup(commandPath(parent.Node()), flag.Name)
+	if !ok {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// load reads the configuration file named by --config, or the default one, on first use
+// and selects the profile named by --profile
+func (r *configResolver) load(ctx *kong.Context) error {
+	if r.file != nil || r.err != nil {
+		return r.err
+	}
+	r.err = r.loadFile(ctx)
+	return r.err
+}
+
+func (r *configResolver) loadFile(ctx *kong.Context) error {
+	path := stringFlag(ctx, "config")
+	required := path != ""
+	if path == "" {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			return err
+		}
+	}
+
+	file, err := config.LoadFile(path, required)
+	if err != nil {
+		return err
+	}
+
+	if profile := stringFlag(ctx, "profile"); profile != "" {
+		if err := file.SelectProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	r.file = file
+	return nil
+}
+
+// stringFlag returns the value of a string flag, which may come from its environment variable
+func stringFlag(ctx *kong.Context, name string) string {
+	for _, flag := range ctx.Flags() {
+		if flag.Name == name {
+			value, _ := ctx.FlagValue(flag).(string)
+			return value
+		}
+	}
+	return ""
+}
+
+// commandPath returns the names of the commands leading to a node, e.g. ["notify", "slack"]
+func commandPath(node *kong.Node) []string {
+	var names []string
+	for ; node != nil; node = node.Parent {
+		if node.Type == kong.CommandNode {
+			names = append([]string{node.Name}, names...)
+		}
+	}
+	return names
+}
+
+// File returns the loaded configuration file, or an empty one when none was loaded
+func (r *configResolver) File() *config.File {
+	if r.file == nil {
+		return &config.File{}
+	}
+	return r.file
+}
@@ -0,0 +1,134 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+owner: acme
+data_dir: /var/lib/gwm
+list:
+  days: 14
+stats:
+  output: json
+  sort: rate
+notify:
+  slack:
+    webhook_url: https://hooks.slack.com/services/default
+    route:
+      api: https://hooks.slack.com/services/api
+`
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestParseConfigFile(t *testing.T) {
+	path := writeConfig(t, testConfig)
+
+	tests := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		check func(t *testing.T, c *cli.CLI)
+	}{
+		{
+			name: "file fills in unset flags",
+			args: []string{"--config", path, "list"},
+			check: func(t *testing.T, c *cli.CLI) {
+				assert.Equal(t, 14, c.List.Days)
+				assert.Equal(t, "/var/lib/gwm", c.DataDir)
+			},
+		},
+		{
+			name: "flags override the file",
+			args: []string{"--config", path, "stats", "--output", "csv"},
+			check: func(t *testing.T, c *cli.CLI) {
+				assert.Equal(t, "csv", c.Stats.Output)
+				assert.Equal(t, "rate", c.Stats.Sort)
+			},
+		},
+		{
+			name: "environment overrides the file",
+			args: []string{"--config", path, "notify", "slack"},
+			env:  map[string]string{"SLACK_WEBHOOK_URL": "https://hooks.slack.com/services/env"},
+			check: func(t *testing.T, c *cli.CLI) {
+				assert.Equal(t, "https://hooks.slack.com/services/env", c.Notify.Slack.WebhookURL)
+				assert.Equal(t, map[string]string{"api": "https://hooks.slack.com/services/api"}, c.Notify.Slack.Route)
+			},
+		},
+		{
+			name: "config file from the environment",
+			args: []string{"list"},
+			env:  map[string]string{"GWM_CONFIG": path},
+			check: func(t *testing.T, c *cli.CLI) {
+				assert.Equal(t, 14, c.List.Days)
+			},
+		},
+		{
+			name: "defaults without a config file",
+			args: []string{"list"},
+			env:  map[string]string{"XDG_CONFIG_HOME": t.TempDir()},
+			check: func(t *testing.T, c *cli.CLI) {
+				assert.Equal(t, 7, c.List.Days)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			c, _, err := cli.Parse(tt.args)
+			require.NoError(t, err)
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestParseMissingConfigFile(t *testing.T) {
+	_, _, err := cli.Parse([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml"), "list"})
+	assert.Error(t, err)
+}
+
+func TestParseProfile(t *testing.T) {
+	path := writeConfig(t, testConfig+`
+profiles:
+  personal:
+    owner: octocat
+    list:
+      days: 30
+`)
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	c, _, err := cli.Parse([]string{"--config", path, "--profile", "personal", "list"})
+	require.NoError(t, err)
+	assert.Equal(t, 30, c.List.Days)
+	// The data directory from the rest of the file still applies
+	assert.Equal(t, "/var/lib/gwm", c.DataDir)
+
+	path = writeConfig(t, `
+profiles:
+  personal:
+    owner: octocat
+`)
+	t.Setenv("GWM_PROFILE", "personal")
+	c, _, err = cli.Parse([]string{"--config", path, "list"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dataHome, "gh-workflow-monitor", "profiles", "personal"), c.DataDir)
+
+	_, _, err = cli.Parse([]string{"--config", path, "--profile", "missing", "list"})
+	assert.Error(t, err)
+}
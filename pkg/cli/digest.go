@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/digest"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+)
+
+// DigestOptions configures the digest command
+type DigestOptions struct {
+	Days int
+	// Recipients maps email addresses to the repositories they receive; an empty list means all
+	Recipients map[string][]string
+	SMTP       digest.SMTPConfig
+	DryRun     bool
+}
+
+// ParseRecipients combines recipients of every repository with recipients of comma-separated
+// lists of repositories
+func ParseRecipients(all []string, scoped map[string]string) map[string][]string {
+	recipients := make(map[string][]string, len(all)+len(scoped))
+	for _, addr := range all {
+		recipients[addr] = nil
+	}
+	for addr, repos := range scoped {
+		for _, repo := range strings.Split(repos, ",") {
+			if repo = strings.TrimSpace(repo); repo != "" {
+				recipients[addr] = append(recipients[addr], repo)
+			}
+		}
+	}
+	return recipients
+}
+
+// HandleDigest handles the digest command, emailing each recipient a summary of the
+// failures in their repositories, on pull requests and branches alike
+func HandleDigest(client github.Client, opts DigestOptions) error {
+	if len(opts.Recipients) == 0 {
+		return fmt.Errorf("no recipients: set --to or --recipient")
+	}
+	if !opts.DryRun && (opts.SMTP.Host == "" || opts.SMTP.From == "") {
+		return fmt.Errorf("SMTP server and sender are required: set --smtp-host and --from")
+	}
+
+	ctx := context.Background()
+	runs, _, err := client.SyncRuns(ctx, opts.Days, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list workflow runs: %w", err)
+	}
+
+	var failures []github.WorkflowFailure
+	for _, run := range runs {
+		if run.Failed() {
+			failures = append(failures, run.Failure())
+		}
+	}
+
+	// Recipients of only some repositories are the owners of those repositories
+	owners := make(map[string][]string)
+	for addr, repos := range opts.Recipients {
+		if len(repos) > 0 {
+			owners[addr] = repos
+		}
+	}
+
+	until := time.Now()
+	since := until.AddDate(0, 0, -opts.Days)
+
+	addrs := make([]string, 0, len(opts.Recipients))
+	for addr := range opts.Recipients {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	for _, addr := range addrs {
+		d := digest.Build(failures, since, until, opts.Recipients[addr], owners)
+
+		if opts.DryRun {
+			fmt.Printf("To: %s\nSubject: %s\n\n%s\n", addr, d.Subject(), d.Text())
+			continue
+		}
+
+		html, err := d.HTML()
+		if err != nil {
+			return err
+		}
+		if err := digest.Send(opts.SMTP, []string{addr}, d.Subject(), d.Text(), html); err != nil {
+			return fmt.Errorf("failed to send digest to %s: %w", addr, err)
+		}
+		fmt.Printf("Sent digest of %d failure(s) to %s\n", d.Total(), addr)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package cli_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/cli"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseRecipients(t *testing.T) {
+	recipients := cli.ParseRecipients(
+		[]string{"eng@example.com"},
+		map[string]string{"lead@example.com": "api, web,"},
+	)
+
+	assert.Equal(t, map[string][]string{
+		"eng@example.com":  nil,
+		"lead@example.com": {"api", "web"},
+	}, recipients)
+}
+
+func TestHandleDigest(t *testing.T) {
+	runs := []github.RunSummary{
+		{ID: 1, Repo: "api", PRNumber: 1, Workflow: "CI", Conclusion: "failure", CreatedAt: time.Now().Add(-time.Hour)},
+		// Failures on the default branch are included too
+		{ID: 2, Repo: "api", Workflow: "Deploy", Branch: "main", Conclusion: "failure", CreatedAt: time.Now().Add(-time.Hour)},
+		{ID: 3, Repo: "api", Workflow: "Lint", Branch: "main", Conclusion: "success", CreatedAt: time.Now().Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name    string
+		opts    cli.DigestOptions
+		mock    bool
+		wantErr bool
+		want    []string
+	}{
+		{
+			name: "dry run",
+			opts: cli.DigestOptions{Days: 1, Recipients: map[string][]string{"lead@example.com": {"api"}}, DryRun: true},
+			mock: true,
+			want: []string{"2 failed run(s)", "CI failure on PR #1", "Deploy failure on main", "lead@example.com: 2"},
+		},
+		{
+			name:    "no recipients",
+			opts:    cli.DigestOptions{Days: 1, DryRun: true},
+			wantErr: true,
+		},
+		{
+			name:    "no SMTP server",
+			opts:    cli.DigestOptions{Days: 1, Recipients: map[string][]string{"lead@example.com": nil}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := mocks.NewMockClient(t)
+			if tt.mock {
+				mockClient.EXPECT().SyncRuns(mock.Anything, 1, map[string]github.Watermark(nil)).Return(runs, nil, nil)
+			}
+
+			var err error
+			out := captureStdout(t, func() {
+				err = cli.HandleDigest(mockClient, tt.opts)
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			for _, want := range tt.want {
+				assert.Contains(t, out, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+)
+
+// HandleDoctor handles the doctor command. It loads the configuration and creates a client
+// with newClient and, when that works, checks the setup against the API. It prints a
+// checklist and fails when any check failed.
+func HandleDoctor(newClient func() (github.Client, error)) error {
+	config := github.Diagnosis{Name: "Configuration", Status: github.DiagnosisPass, Detail: "token and owner found"}
+
+	client, err := newClient()
+	if err != nil {
+		config.Status, config.Detail = github.DiagnosisFail, err.Error()
+		config.Hint = "Set GITHUB_TOKEN and GITHUB_OWNER, add them to the configuration file, or log in with `gh auth login`"
+	}
+
+	results := []github.Diagnosis{config}
+	if client != nil {
+		results = append(results, client.Diagnose(context.Background())...)
+	}
+
+	failed := 0
+	for _, result := range results {
+		fmt.Printf("[%s] %s", strings.ToUpper(result.Status), result.Name)
+		if result.Detail != "" {
+			fmt.Printf(": %s", result.Detail)
+		}
+		fmt.Println()
+		if result.Hint != "" && result.Status != github.DiagnosisPass {
+			fmt.Printf("       hint: %s\n", result.Hint)
+		}
+		if result.Failed() {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	fmt.Println("\nEverything looks good")
+	return nil
+}
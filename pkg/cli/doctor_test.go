@@ -0,0 +1,62 @@
+package cli_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/cli"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleDoctor(t *testing.T) {
+	tests := []struct {
+		name      string
+		results   []github.Diagnosis
+		configErr error
+		wantErr   bool
+	}{
+		{
+			name: "all checks pass",
+			results: []github.Diagnosis{
+				{Name: "API reachable", Status: github.DiagnosisPass},
+				{Name: "Token scopes", Status: github.DiagnosisWarn, Hint: "Add the repo scope"},
+			},
+		},
+		{
+			name: "a check fails",
+			results: []github.Diagnosis{
+				{Name: "API reachable", Status: github.DiagnosisPass},
+				{Name: "Authentication", Status: github.DiagnosisFail, Hint: "Create a new token"},
+			},
+			wantErr: true,
+		},
+		{
+			name:      "configuration fails",
+			configErr: fmt.Errorf("GITHUB_TOKEN environment variable is required"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newClient := func() (github.Client, error) {
+				if tt.configErr != nil {
+					return nil, tt.configErr
+				}
+				m := mocks.NewMockClient(t)
+				m.EXPECT().Diagnose(mock.Anything).Return(tt.results)
+				return m, nil
+			}
+
+			err := cli.HandleDoctor(newClient)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/store"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
+)
+
+// HandleNotify handles the notify commands. Failed runs created within the last days days,
+// on pull requests and branches alike, that the notifier has not announced before are
+// announced, and announced failures whose workflow has since succeeded are announced as resolved.
+func HandleNotify(client github.Client, state notify.State, notifier notify.Notifier, days int) error {
+	ctx := context.Background()
+
+	runs, _, err := client.SyncRuns(ctx, days, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list workflow runs: %w", err)
+	}
+
+	var failures []github.WorkflowFailure
+	for _, run := range runs {
+		if run.Failed() {
+			failures = append(failures, run.Failure())
+		}
+	}
+
+	// A failure that cannot be announced must not hold back the resolution of others
+	sent, announceErr := notify.Announce(ctx, notifier, state, failures)
+	resolved, resolveErr := notify.Resolve(ctx, notifier, state, runs)
+
+	if sent == 0 && resolved == 0 {
+		fmt.Println("Nothing new to announce")
+	} else {
+		fmt.Printf("Sent %d failure and %d resolved %s notification(s)\n", sent, resolved, notifier.Name())
+	}
+	return errors.Join(announceErr, resolveErr)
+}
+
+// HandleSnooze handles the snooze command, silencing notifications for a repository or
+// one of its workflows for the given duration. A zero duration removes the snooze.
+func HandleSnooze(history *store.Store, repo, workflow string, duration time.Duration) error {
+	if repo == "" {
+		return fmt.Errorf("repository name is required")
+	}
+
+	target := repo
+	if workflow != "" {
+		target += " / " + workflow
+	}
+
+	if duration <= 0 {
+		if err := history.DeleteSnooze(repo, workflow); err != nil {
+			return err
+		}
+		fmt.Printf("Notifications for %s are no longer snoozed\n", target)
+		return nil
+	}
+
+	until := time.Now().Add(duration)
+	if err := history.SaveSnooze(store.Snooze{Repo: repo, Workflow: workflow, Until: until}); err != nil {
+		return err
+	}
+	fmt.Printf("Notifications for %s are snoozed until %s\n", target, until.Format(time.RFC1123))
+	return nil
+}
+
+// HandleSnoozeList lists the active snoozes
+func HandleSnoozeList(history *store.Store) error {
+	snoozes, err := history.Snoozes(time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(snoozes) == 0 {
+		fmt.Println("No snoozed repositories or workflows")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tWORKFLOW\tUNTIL")
+	for _, snooze := range snoozes {
+		workflow := snooze.Workflow
+		if workflow == "" {
+			workflow = "(all)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", snooze.Repo, workflow, snooze.Until.Format(time.RFC1123))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,115 @@
+package cli_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/store"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/cli"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github/mocks"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleNotify(t *testing.T) {
+	var posted atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted.Add(1)
+	}))
+	defer server.Close()
+
+	history, err := store.Open(t.TempDir())
+	require.NoError(t, err)
+	defer history.Close()
+
+	now := time.Now()
+	failing := []github.RunSummary{
+		{ID: 1, Repo: "repo1", PRNumber: 1, Workflow: "CI", Branch: "feature", Conclusion: "failure", CreatedAt: now.Add(-2 * time.Hour)},
+		// Failures on the default branch have no pull request and are announced too
+		{ID: 2, Repo: "repo1", Workflow: "Deploy", Branch: "main", Conclusion: "failure", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: 3, Repo: "repo1", Workflow: "Lint", Branch: "main", Conclusion: "success", CreatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	mockClient := mocks.NewMockClient(t)
+	mockClient.EXPECT().SyncRuns(mock.Anything, 1, map[string]github.Watermark(nil)).Return(failing, nil, nil).Twice()
+
+	slack := notify.NewSlack(server.URL, nil)
+	require.NoError(t, cli.HandleNotify(mockClient, history, slack, 1))
+	assert.Equal(t, int32(2), posted.Load())
+
+	// The same runs are not announced twice
+	require.NoError(t, cli.HandleNotify(mockClient, history, slack, 1))
+	assert.Equal(t, int32(2), posted.Load())
+
+	// Once the workflow succeeds on the same branch the failure is resolved
+	mockClient.EXPECT().SyncRuns(mock.Anything, 1, map[string]github.Watermark(nil)).Return(append(failing,
+		github.RunSummary{ID: 4, Repo: "repo1", Workflow: "Deploy", Branch: "main", Conclusion: "success", CreatedAt: now},
+	), nil, nil).Once()
+	require.NoError(t, cli.HandleNotify(mockClient, history, slack, 1))
+	assert.Equal(t, int32(3), posted.Load())
+}
+
+func TestHandleSnooze(t *testing.T) {
+	history, err := store.Open(t.TempDir())
+	require.NoError(t, err)
+	defer history.Close()
+
+	assert.Error(t, cli.HandleSnooze(history, "", "", time.Hour))
+
+	require.NoError(t, cli.HandleSnooze(history, "repo1", "CI", time.Hour))
+	snoozed, err := history.Snoozed("repo1", "CI", time.Now())
+	require.NoError(t, err)
+	assert.True(t, snoozed)
+	require.NoError(t, cli.HandleSnoozeList(history))
+
+	require.NoError(t, cli.HandleSnooze(history, "repo1", "CI", 0))
+	snoozed, err = history.Snoozed("repo1", "CI", time.Now())
+	require.NoError(t, err)
+	assert.False(t, snoozed)
+}
+
+func TestHandleNotifyResolvesDespiteFailedAnnouncements(t *testing.T) {
+	var failing atomic.Bool
+	var resolved atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "recovered in") {
+			resolved.Add(1)
+			return
+		}
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	history, err := store.Open(t.TempDir())
+	require.NoError(t, err)
+	defer history.Close()
+
+	now := time.Now()
+	announced := github.RunSummary{ID: 1, Repo: "repo1", Workflow: "CI", Branch: "main", Conclusion: "failure", CreatedAt: now.Add(-2 * time.Hour)}
+
+	mockClient := mocks.NewMockClient(t)
+	mockClient.EXPECT().SyncRuns(mock.Anything, 1, map[string]github.Watermark(nil)).Return([]github.RunSummary{announced}, nil, nil).Once()
+	slack := notify.NewSlack(server.URL, nil)
+	require.NoError(t, cli.HandleNotify(mockClient, history, slack, 1))
+
+	// A new failure cannot be announced, but the recovery of the first is
+	failing.Store(true)
+	mockClient.EXPECT().SyncRuns(mock.Anything, 1, map[string]github.Watermark(nil)).Return([]github.RunSummary{
+		announced,
+		{ID: 2, Repo: "repo1", Workflow: "CI", Branch: "main", Conclusion: "success", CreatedAt: now.Add(-time.Hour)},
+		{ID: 3, Repo: "repo1", Workflow: "Lint", Branch: "main", Conclusion: "failure", CreatedAt: now},
+	}, nil, nil).Once()
+	assert.Error(t, cli.HandleNotify(mockClient, history, slack, 1))
+	assert.Equal(t, int32(1), resolved.Load())
+}
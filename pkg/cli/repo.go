@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/config"
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/repo"
+)
+
+// ResolveRepo checks the host of a repository against the API base URL and returns the
+// base URL to use. A host that does not match a configured base URL is an error. When no
+// base URL is configured, a GitHub Enterprise Server host selects its API only if
+// hostToken, e.g. config.GHToken, has a token for that host; that token is returned too
+// and must replace the configured one, which belongs to another host.
+func ResolveRepo(target repo.Repo, baseURL string, hostToken func(host string) string) (string, string, error) {
+	if target.Host == "" {
+		return baseURL, "", nil
+	}
+
+	host, err := apiHost(baseURL)
+	if err != nil {
+		return "", "", err
+	}
+	if strings.EqualFold(target.Host, host) {
+		return baseURL, "", nil
+	}
+	if baseURL == "" {
+		token := hostToken(target.Host)
+		if token == "" {
+			return "", "", fmt.Errorf("unknown host %s; pass --base-url", target.Host)
+		}
+		return "https://" + target.Host + "/api/v3", token, nil
+	}
+
+	return "", "", fmt.Errorf("repository %s/%s is not on %s, the host of the API base URL %s", target.Host, target.FullName(), host, baseURL)
+}
+
+// apiHost returns the web host of the GitHub instance a base URL points to
+func apiHost(baseURL string) (string, error) {
+	if baseURL == "" {
+		return repo.DefaultHost, nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid base URL %q", baseURL)
+	}
+	if strings.EqualFold(u.Hostname(), "api.github.com") {
+		return repo.DefaultHost, nil
+	}
+	return u.Host, nil
+}
+
+// RepoName validates a repository given to a command that only knows repositories of
+// the configured owner, such as the local history, and returns its name. The repository
+// may be qualified with the configured owner and the host of the API base URL.
+func RepoName(value, owner, baseURL string) (string, error) {
+	target, err := repo.ParseArg(value)
+	if err != nil {
+		return "", err
+	}
+
+	if target.Host != "" {
+		host, err := apiHost(baseURL)
+		if err != nil {
+			return "", err
+		}
+		if !strings.EqualFold(target.Host, host) {
+			return "", fmt.Errorf("repository %q is not on %s, the host of the API", value, host)
+		}
+	}
+	if target.Owner != "" && !strings.EqualFold(target.Owner, owner) {
+		if owner == "" {
+			return "", fmt.Errorf("repository %q names an owner, but no owner is configured: set GITHUB_OWNER or owner", value)
+		}
+		return "", fmt.Errorf("repository %q is not of the configured owner %s; the local history, snoozes and routes only cover the configured owner (use --profile for another one)", value, owner)
+	}
+
+	return target.Name, nil
+}
+
+// repoName validates a repository against the configured owner and base URL
+func (c *CLI) repoName(value string) (string, error) {
+	return RepoName(value, config.Owner(c.file), c.BaseURL)
+}
+
+// resolveRepoArg applies a repository argument of a command that calls the API, given
+// as NAME, OWNER/NAME or HOST/OWNER/NAME. Like the legacy CLI, an owner overrides the
+// configured one. It returns the repository name and owner.
+func (c *CLI) resolveRepoArg(value string) (string, string, error) {
+	target, err := repo.ParseArg(value)
+	if err != nil {
+		return "", "", err
+	}
+	if c.BaseURL, c.hostToken, err = ResolveRepo(target, c.BaseURL, config.GHToken); err != nil {
+		return "", "", err
+	}
+	return target.Name, target.Owner, nil
+}
+
+// InferOwner returns the owner of a repository given to a command that takes one, or the
+// owner of the current repository when neither the argument nor the configuration names
+// one. The inferred owner is printed, since the command may otherwise act on an
+// unexpected organization. An empty result means the configured owner.
+func InferOwner(owner, configured string) string {
+	if owner != "" || configured != "" {
+		return owner
+	}
+	detected, err := repo.Detect()
+	if err != nil {
+		return ""
+	}
+	fmt.Fprintf(os.Stderr, "Using owner %s of the current repository\n", detected.Owner)
+	return detected.Owner
+}
@@ -0,0 +1,93 @@
+package cli_test
+
+import (
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/repo"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRepo(t *testing.T) {
+	// Only ghe.example.com has a gh login
+	hostToken := func(host string) string {
+		if host == "ghe.example.com" {
+			return "gho_enterprise"
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name      string
+		target    repo.Repo
+		baseURL   string
+		want      string
+		wantToken string
+		wantErr   bool
+	}{
+		{name: "no host", target: repo.Repo{Owner: "acme", Name: "api"}, baseURL: "https://ghe.example.com/api/v3", want: "https://ghe.example.com/api/v3"},
+		{name: "github.com", target: repo.Repo{Host: "github.com", Owner: "acme", Name: "api"}},
+		{name: "github.com with public API URL", target: repo.Repo{Host: "github.com", Owner: "acme", Name: "api"}, baseURL: "https://api.github.com/", want: "https://api.github.com/"},
+		{name: "enterprise host selects its API and login", target: repo.Repo{Host: "ghe.example.com", Owner: "acme", Name: "api"}, want: "https://ghe.example.com/api/v3", wantToken: "gho_enterprise"},
+		{name: "unknown host", target: repo.Repo{Host: "git.example.org", Owner: "acme", Name: "api"}, wantErr: true},
+		{name: "enterprise host matches base URL", target: repo.Repo{Host: "ghe.example.com", Owner: "acme", Name: "api"}, baseURL: "https://ghe.example.com/api/v3", want: "https://ghe.example.com/api/v3"},
+		{name: "host does not match base URL", target: repo.Repo{Host: "github.com", Owner: "acme", Name: "api"}, baseURL: "https://ghe.example.com/api/v3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, token, err := cli.ResolveRepo(tt.target, tt.baseURL, hostToken)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantToken, token)
+		})
+	}
+}
+
+func TestRepoName(t *testing.T) {
+	tests := []struct {
+		value   string
+		baseURL string
+		want    string
+		wantErr bool
+	}{
+		{value: "api", want: "api"},
+		{value: "acme/api", want: "api"},
+		{value: "ACME/api", want: "api"},
+		{value: "github.com/acme/api", want: "api"},
+		{value: "ghe.example.com/acme/api", baseURL: "https://ghe.example.com/api/v3", want: "api"},
+		{value: "other/api", wantErr: true},
+		{value: "ghe.example.com/acme/api", wantErr: true},
+		{value: "a/b/c/d", wantErr: true},
+		{value: "my repo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := cli.RepoName(tt.value, "acme", tt.baseURL)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := cli.RepoName("acme/api", "", "")
+	assert.Error(t, err)
+}
+
+func TestInferOwner(t *testing.T) {
+	t.Setenv("GH_REPO", "octocat/api")
+
+	assert.Equal(t, "other", cli.InferOwner("other", ""))
+	// An empty owner keeps the configured one
+	assert.Equal(t, "", cli.InferOwner("", "acme"))
+	assert.Equal(t, "octocat", cli.InferOwner("", ""))
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/exporter"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/webhook"
+)
+
+// ServeOptions configures the serve command
+type ServeOptions struct {
+	Addr          string
+	Metrics       bool
+	Webhook       bool
+	WebhookSecret string
+	Interval      time.Duration
+	Days          int
+	// Notifiers announce the failures delivered to the webhook endpoint
+	Notifiers []notify.Notifier
+	// State records which failures the notifiers have announced
+	State notify.State
+}
+
+// HandleServe handles the serve command. It blocks until ctx is cancelled.
+func HandleServe(ctx context.Context, client github.Client, opts ServeOptions) error {
+	if !opts.Metrics && !opts.Webhook {
+		return fmt.Errorf("nothing to serve: enable --metrics and/or --webhook")
+	}
+	if opts.Webhook && opts.WebhookSecret == "" {
+		return fmt.Errorf("--webhook requires a secret: set --webhook-secret or GITHUB_WEBHOOK_SECRET")
+	}
+	if len(opts.Notifiers) > 0 && !opts.Webhook {
+		return fmt.Errorf("--notify requires --webhook")
+	}
+
+	mux := http.NewServeMux()
+	sinks := []webhook.Sink{webhook.LogSink}
+
+	if opts.Metrics {
+		exp := exporter.New(client, opts.Days)
+		mux.Handle("/metrics", exp)
+		go exp.Run(ctx, opts.Interval)
+		sinks = append(sinks, exp)
+		log.Printf("Serving metrics on %s/metrics", opts.Addr)
+	}
+
+	var handler *webhook.Handler
+	if opts.Webhook {
+		// Queued failures are still announced while shutting down
+		notifyCtx := context.WithoutCancel(ctx)
+		for _, notifier := range opts.Notifiers {
+			sinks = append(sinks, notify.NewSink(notifyCtx, notifier, opts.State))
+			log.Printf("Announcing webhook failures through %s", notifier.Name())
+		}
+		handler = webhook.NewHandler(opts.WebhookSecret, sinks...)
+		mux.Handle("/webhook", handler)
+		log.Printf("Receiving webhooks on %s/webhook", opts.Addr)
+	}
+
+	server := &http.Server{
+		Addr:              opts.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if handler != nil && err == nil {
+		// Announce the failures that were acknowledged but not yet passed on
+		handler.Close()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/store"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/stats"
+)
+
+// HandleStats handles the stats command. With trend set, a chart of failures per day follows the table.
+// When history is not nil, new runs are synced into it and the statistics are computed from the
+// recorded runs of the window; otherwise the runs of the window are fetched from the API.
+func HandleStats(client github.Client, history *store.Store, days int, by, sortBy, output string, trend, ascii bool) error {
+	if trend && output != "table" {
+		return fmt.Errorf("--trend is only supported with table output")
+	}
+
+	var runs []github.RunSummary
+	var err error
+	if history != nil {
+		if err = syncRuns(client, history, days, false); err == nil {
+			runs, err = recordedRuns(history, store.Filter{Since: time.Now().AddDate(0, 0, -days)})
+		}
+	} else {
+		runs, err = client.ListCompletedRuns(context.Background(), days)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list workflow runs: %w", err)
+	}
+
+	rows, err := stats.Aggregate(runs, by)
+	if err != nil {
+		return err
+	}
+	if err := stats.Sort(rows, sortBy); err != nil {
+		return err
+	}
+
+	switch output {
+	case "json":
+		return writeStatsJSON(os.Stdout, rows)
+	case "csv":
+		return writeStatsCSV(os.Stdout, rows)
+	default:
+		if len(rows) == 0 {
+			fmt.Printf("No completed workflow runs found in the last %d days\n", days)
+			return nil
+		}
+		fmt.Printf("Workflow statistics for the last %d days:\n\n", days)
+		if err := writeStatsTable(os.Stdout, rows, by); err != nil {
+			return err
+		}
+		if trend {
+			fmt.Printf("\nFailures per day (oldest to newest):\n\n")
+			return writeTrend(os.Stdout, rows, stats.DailyFailures(runs, by, days, time.Now()), ascii)
+		}
+		return nil
+	}
+}
+
+// writeTrend renders one sparkline per row, in the same order as the statistics table
+func writeTrend(w io.Writer, rows []stats.Row, trends []stats.Trend, ascii bool) error {
+	byName := make(map[string]stats.Trend, len(trends))
+	for _, t := range trends {
+		byName[t.Repo+"\x00"+t.Workflow] = t
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		t, ok := byName[row.Repo+"\x00"+row.Workflow]
+		if !ok {
+			continue
+		}
+
+		name := row.Repo
+		if row.Workflow != "" {
+			name += " / " + row.Workflow
+		}
+
+		peak := 0
+		for _, count := range t.Daily {
+			if count > peak {
+				peak = count
+			}
+		}
+		fmt.Fprintf(tw, "%s\t|%s|\t%d total, peak %d/day\n", name, stats.Sparkline(t.Daily, ascii), t.Total(), peak)
+	}
+
+	return tw.Flush()
+}
+
+// statsRecord is the exported form of a statistics row, with durations in seconds
+type statsRecord struct {
+	Repo                string  `json:"repo"`
+	Workflow            string  `json:"workflow,omitempty"`
+	Runs                int     `json:"runs"`
+	Failures            int     `json:"failures"`
+	FailureRate         float64 `json:"failure_rate"`
+	MeanDurationSeconds float64 `json:"mean_duration_seconds"`
+	P95DurationSeconds  float64 `json:"p95_duration_seconds"`
+	MTTRSeconds         float64 `json:"mttr_seconds"`
+	Recoveries          int     `json:"recoveries"`
+}
+
+func newStatsRecord(row stats.Row) statsRecord {
+	return statsRecord{
+		Repo:                row.Repo,
+		Workflow:            row.Workflow,
+		Runs:                row.Runs,
+		Failures:            row.Failures,
+		FailureRate:         row.FailureRate,
+		MeanDurationSeconds: row.MeanDuration.Seconds(),
+		P95DurationSeconds:  row.P95Duration.Seconds(),
+		MTTRSeconds:         row.MTTR.Seconds(),
+		Recoveries:          row.Recoveries,
+	}
+}
+
+func writeStatsJSON(w io.Writer, rows []stats.Row) error {
+	records := make([]statsRecord, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, newStatsRecord(row))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+func writeStatsCSV(w io.Writer, rows []stats.Row) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"repo", "workflow", "runs", "failures", "failure_rate", "mean_duration_seconds", "p95_duration_seconds", "mttr_seconds", "recoveries"})
+	for _, row := range rows {
+		r := newStatsRecord(row)
+		_ = cw.Write([]string{
+			r.Repo,
+			r.Workflow,
+			strconv.Itoa(r.Runs),
+			strconv.Itoa(r.Failures),
+			strconv.FormatFloat(r.FailureRate, 'f', 4, 64),
+			strconv.FormatFloat(r.MeanDurationSeconds, 'f', 0, 64),
+			strconv.FormatFloat(r.P95DurationSeconds, 'f', 0, 64),
+			strconv.FormatFloat(r.MTTRSeconds, 'f', 0, 64),
+			strconv.Itoa(r.Recoveries),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeStatsTable(w io.Writer, rows []stats.Row, by string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if by == stats.ByWorkflow {
+		fmt.Fprintln(tw, "REPOSITORY\tWORKFLOW\tRUNS\tFAILURES\tRATE\tMEAN\tP95\tMTTR")
+	} else {
+		fmt.Fprintln(tw, "REPOSITORY\tRUNS\tFAILURES\tRATE\tMEAN\tP95\tMTTR")
+	}
+
+	for _, row := range rows {
+		name := row.Repo
+		if by == stats.ByWorkflow {
+			name += "\t" + row.Workflow
+		}
+		mttr := "-"
+		if row.Recoveries > 0 {
+			mttr = row.MTTR.Round(time.Minute).String()
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\t%s\t%s\t%s\n",
+			name, row.Runs, row.Failures, row.FailureRate*100,
+			row.MeanDuration.Round(time.Second), row.P95Duration.Round(time.Second), mttr)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,160 @@
+package cli_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/internal/store"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/cli"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleStats(t *testing.T) {
+	now := time.Now()
+	runs := []github.RunSummary{
+		{ID: 2, Repo: "repo1", Workflow: "CI", Branch: "main", Conclusion: "failure", CreatedAt: now.Add(-2 * time.Hour), StartedAt: now.Add(-2 * time.Hour), UpdatedAt: now.Add(-time.Hour)},
+		{ID: 3, Repo: "repo1", Workflow: "CI", Branch: "main", Conclusion: "success", CreatedAt: now.Add(-time.Hour), StartedAt: now.Add(-time.Hour), UpdatedAt: now},
+	}
+	// Recorded by an earlier sync and not returned again
+	recorded := store.Run{ID: 1, Repo: "repo2", Workflow: "Lint", Branch: "main", Conclusion: "success", StartedAt: now.Add(-3 * time.Hour), UpdatedAt: now.Add(-3*time.Hour + time.Minute)}
+
+	tests := []struct {
+		name      string
+		setupMock func(*mocks.MockClient)
+		by        string
+		sortBy    string
+		output    string
+		trend     bool
+		wantErr   bool
+		// wantRows are the expected table rows, split into fields
+		wantRows [][]string
+		want     []string
+	}{
+		{
+			name: "table by workflow",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().SyncRuns(mock.Anything, 30, mock.Anything).Return(runs, nil, nil)
+			},
+			by: "workflow", sortBy: "failures", output: "table",
+			wantRows: [][]string{
+				{"REPOSITORY", "WORKFLOW", "RUNS", "FAILURES", "RATE", "MEAN", "P95", "MTTR"},
+				{"repo1", "CI", "2", "1", "50.0%", "1h0m0s", "1h0m0s", "1h0m0s"},
+				{"repo2", "Lint", "1", "0", "0.0%", "1m0s", "1m0s", "-"},
+			},
+		},
+		{
+			name: "json by repo",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().SyncRuns(mock.Anything, 30, mock.Anything).Return(runs, nil, nil)
+			},
+			by: "repo", sortBy: "mttr", output: "json",
+			want: []string{`"repo": "repo1"`, `"failures": 1`, `"mttr_seconds": 3600`, `"repo": "repo2"`},
+		},
+		{
+			name: "csv",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().SyncRuns(mock.Anything, 30, mock.Anything).Return(runs, nil, nil)
+			},
+			by: "workflow", sortBy: "rate", output: "csv",
+			want: []string{"repo1,CI,2,1,0.5000,3600,3600,3600,1\n", "repo2,Lint,1,0,0.0000,60,60,0,0\n"},
+		},
+		{
+			name: "table with trend",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().SyncRuns(mock.Anything, 30, mock.Anything).Return(runs, nil, nil)
+			},
+			by: "repo", sortBy: "failures", output: "table", trend: true,
+			want: []string{"Failures per day", "1 total, peak 1/day"},
+		},
+		{
+			name:      "trend with csv",
+			setupMock: func(m *mocks.MockClient) {},
+			by:        "repo", sortBy: "failures", output: "csv", trend: true,
+			wantErr: true,
+		},
+		{
+			name: "error from client",
+			setupMock: func(m *mocks.MockClient) {
+				m.EXPECT().SyncRuns(mock.Anything, 30, mock.Anything).Return(nil, nil, fmt.Errorf("mock error"))
+			},
+			by: "workflow", sortBy: "failures", output: "table",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := store.Open(t.TempDir())
+			require.NoError(t, err)
+			defer history.Close()
+			_, err = history.SaveRuns([]store.Run{recorded})
+			require.NoError(t, err)
+
+			mockClient := mocks.NewMockClient(t)
+			tt.setupMock(mockClient)
+
+			out := captureStdout(t, func() {
+				err = cli.HandleStats(mockClient, history, 30, tt.by, tt.sortBy, tt.output, tt.trend, false)
+			})
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if tt.wantRows != nil {
+				var rows [][]string
+				for _, line := range strings.Split(out, "\n")[2:] {
+					if fields := strings.Fields(line); len(fields) > 0 {
+						rows = append(rows, fields)
+					}
+				}
+				assert.Equal(t, tt.wantRows, rows)
+			}
+			for _, want := range tt.want {
+				assert.Contains(t, out, want)
+			}
+		})
+	}
+}
+
+func TestHandleStatsWithoutHistory(t *testing.T) {
+	mockClient := mocks.NewMockClient(t)
+	mockClient.EXPECT().ListCompletedRuns(mock.Anything, 30).Return(nil, nil)
+
+	out := captureStdout(t, func() {
+		require.NoError(t, cli.HandleStats(mockClient, nil, 30, "workflow", "failures", "table", false, false))
+	})
+	assert.Equal(t, "No completed workflow runs found in the last 30 days\n", out)
+}
+
+// captureStdout returns what f prints to standard output
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+
+	f()
+	w.Close()
+	return <-out
+}
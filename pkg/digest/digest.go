@@ -0,0 +1,225 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+)
+
+// Digest summarizes the failures of a period
+type Digest struct {
+	Since   time.Time
+	Until   time.Time
+	Repos   []RepoSection
+	Owners  []OwnerCount
+	Authors []AuthorCount
+}
+
+// RepoSection lists the failures of a repository, most recent first
+type RepoSection struct {
+	Repo     string
+	Failures []github.WorkflowFailure
+}
+
+// OwnerCount is the number of failed runs in the repositories an owner is responsible for
+type OwnerCount struct {
+	Owner    string
+	Failures int
+}
+
+// AuthorCount is the number of failed runs triggered by a user
+type AuthorCount struct {
+	Author   string
+	Failures int
+}
+
+// Total returns the number of failures in the digest
+func (d Digest) Total() int {
+	total := 0
+	for _, section := range d.Repos {
+		total += len(section.Failures)
+	}
+	return total
+}
+
+// Build groups the failures into a digest. When repos is not empty, only failures of
+// those repositories are included. owners maps each owner to the repositories they are
+// responsible for; failures of repositories without an owner are counted as "unowned".
+func Build(failures []github.WorkflowFailure, since, until time.Time, repos []string, owners map[string][]string) Digest {
+	allowed := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		allowed[repo] = true
+	}
+	ownedBy := make(map[string][]string)
+	for owner, repos := range owners {
+		for _, repo := range repos {
+			ownedBy[repo] = append(ownedBy[repo], owner)
+		}
+	}
+
+	byRepo := make(map[string][]github.WorkflowFailure)
+	byOwner := make(map[string]int)
+	byAuthor := make(map[string]int)
+	for _, failure := range failures {
+		if len(allowed) > 0 && !allowed[failure.Repo] {
+			continue
+		}
+		if failure.StartedAt.Before(since) || failure.StartedAt.After(until) {
+			continue
+		}
+		byRepo[failure.Repo] = append(byRepo[failure.Repo], failure)
+
+		if len(owners) > 0 {
+			if len(ownedBy[failure.Repo]) == 0 {
+				byOwner["unowned"]++
+			}
+			for _, owner := range ownedBy[failure.Repo] {
+				byOwner[owner]++
+			}
+		}
+
+		author := failure.Actor
+		if author == "" {
+			author = "unknown"
+		}
+		byAuthor[author]++
+	}
+
+	d := Digest{Since: since, Until: until}
+	for repo, failures := range byRepo {
+		sort.Slice(failures, func(i, j int) bool {
+			return failures[i].StartedAt.After(failures[j].StartedAt)
+		})
+		d.Repos = append(d.Repos, RepoSection{Repo: repo, Failures: failures})
+	}
+	sort.Slice(d.Repos, func(i, j int) bool {
+		return d.Repos[i].Repo < d.Repos[j].Repo
+	})
+
+	for owner, count := range byOwner {
+		d.Owners = append(d.Owners, OwnerCount{Owner: owner, Failures: count})
+	}
+	sort.Slice(d.Owners, func(i, j int) bool {
+		if d.Owners[i].Failures != d.Owners[j].Failures {
+			return d.Owners[i].Failures > d.Owners[j].Failures
+		}
+		return d.Owners[i].Owner < d.Owners[j].Owner
+	})
+
+	for author, count := range byAuthor {
+		d.Authors = append(d.Authors, AuthorCount{Author: author, Failures: count})
+	}
+	sort.Slice(d.Authors, func(i, j int) bool {
+		if d.Authors[i].Failures != d.Authors[j].Failures {
+			return d.Authors[i].Failures > d.Authors[j].Failures
+		}
+		return d.Authors[i].Author < d.Authors[j].Author
+	})
+
+	return d
+}
+
+// Subject returns the subject line of the digest email
+func (d Digest) Subject() string {
+	return fmt.Sprintf("Workflow failures %s - %s: %d failed run(s) in %d repositories",
+		d.Since.Format("Jan 2"), d.Until.Format("Jan 2"), d.Total(), len(d.Repos))
+}
+
+// Text renders the plain text version of the digest
+func (d Digest) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Workflow failures from %s to %s\n\n", d.Since.Format(time.RFC1123), d.Until.Format(time.RFC1123))
+
+	if len(d.Repos) == 0 {
+		b.WriteString("No failed workflow runs.\n")
+		return b.String()
+	}
+
+	for _, section := range d.Repos {
+		fmt.Fprintf(&b, "%s (%d)\n", section.Repo, len(section.Failures))
+		for _, failure := range section.Failures {
+			fmt.Fprintf(&b, "  - %s %s", failure.Workflow, failure.Conclusion)
+			if failure.PRNumber != 0 {
+				fmt.Fprintf(&b, " on PR #%d", failure.PRNumber)
+			} else if failure.Branch != "" {
+				fmt.Fprintf(&b, " on %s", failure.Branch)
+			}
+			if failure.Actor != "" {
+				fmt.Fprintf(&b, " by %s", failure.Actor)
+			}
+			fmt.Fprintf(&b, " at %s\n    %s\n", failure.StartedAt.Format(time.RFC1123), failure.URL)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(d.Owners) > 0 {
+		b.WriteString("Failures by owner\n")
+		for _, owner := range d.Owners {
+			fmt.Fprintf(&b, "  %s: %d\n", owner.Owner, owner.Failures)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("Failures by author\n")
+	for _, author := range d.Authors {
+		fmt.Fprintf(&b, "  %s: %d\n", author.Author, author.Failures)
+	}
+
+	return b.String()
+}
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>Workflow failures from {{.Since.Format "Jan 2, 15:04 MST"}} to {{.Until.Format "Jan 2, 15:04 MST"}}</h2>
+{{- if not .Repos}}
+<p>No failed workflow runs.</p>
+{{- end}}
+{{- range .Repos}}
+<h3>{{.Repo}} ({{len .Failures}})</h3>
+<table cellpadding="4" style="border-collapse: collapse">
+<tr><th align="left">Workflow</th><th align="left">Conclusion</th><th align="left">Pull request or branch</th><th align="left">Author</th><th align="left">Started</th></tr>
+{{- range .Failures}}
+<tr>
+<td><a href="{{.URL}}">{{.Workflow}}</a></td>
+<td>{{.Conclusion}}</td>
+<td>{{if .PRNumber}}<a href="{{.PRURL}}">#{{.PRNumber}}</a>{{else}}{{.Branch}}{{end}}</td>
+<td>{{.Actor}}</td>
+<td>{{.StartedAt.Format "Jan 2, 15:04 MST"}}</td>
+</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Owners}}
+<h3>Failures by owner</h3>
+<table cellpadding="4" style="border-collapse: collapse">
+{{- range .Owners}}
+<tr><td>{{.Owner}}</td><td align="right">{{.Failures}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Authors}}
+<h3>Failures by author</h3>
+<table cellpadding="4" style="border-collapse: collapse">
+{{- range .Authors}}
+<tr><td>{{.Author}}</td><td align="right">{{.Failures}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// HTML renders the HTML version of the digest
+func (d Digest) HTML() (string, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, d); err != nil {
+		return "", fmt.Errorf("error rendering digest: %v", err)
+	}
+	return buf.String(), nil
+}
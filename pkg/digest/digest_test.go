@@ -0,0 +1,123 @@
+package digest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/digest"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	until := time.Date(2024, 3, 15, 8, 0, 0, 0, time.UTC)
+	since := until.AddDate(0, 0, -1)
+
+	failures := []github.WorkflowFailure{
+		{RunID: 1, Repo: "web", Workflow: "CI", Conclusion: "failure", Actor: "alice", StartedAt: until.Add(-3 * time.Hour)},
+		{RunID: 2, Repo: "api", Workflow: "CI", Conclusion: "failure", Actor: "bob", StartedAt: until.Add(-2 * time.Hour)},
+		{RunID: 3, Repo: "api", Workflow: "Lint", Conclusion: "timed_out", Actor: "alice", StartedAt: until.Add(-time.Hour)},
+		{RunID: 4, Repo: "api", Workflow: "CI", Conclusion: "failure", Actor: "alice", StartedAt: since.Add(-time.Hour)},
+		{RunID: 5, Repo: "docs", Workflow: "CI", Conclusion: "failure", StartedAt: until.Add(-time.Hour)},
+	}
+	owners := map[string][]string{
+		"api-lead@example.com": {"api"},
+		"web-lead@example.com": {"web", "api"},
+	}
+
+	tests := []struct {
+		name        string
+		repos       []string
+		wantRepos   []string
+		wantOwners  []digest.OwnerCount
+		wantAuthors []digest.AuthorCount
+		wantTotal   int
+	}{
+		{
+			name:        "all repositories",
+			wantRepos:   []string{"api", "docs", "web"},
+			wantOwners:  []digest.OwnerCount{{"web-lead@example.com", 3}, {"api-lead@example.com", 2}, {"unowned", 1}},
+			wantAuthors: []digest.AuthorCount{{"alice", 2}, {"bob", 1}, {"unknown", 1}},
+			wantTotal:   4,
+		},
+		{
+			name:        "only the recipient's repositories",
+			repos:       []string{"api"},
+			wantRepos:   []string{"api"},
+			wantOwners:  []digest.OwnerCount{{"api-lead@example.com", 2}, {"web-lead@example.com", 2}},
+			wantAuthors: []digest.AuthorCount{{"alice", 1}, {"bob", 1}},
+			wantTotal:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := digest.Build(failures, since, until, tt.repos, owners)
+
+			var repos []string
+			for _, section := range d.Repos {
+				repos = append(repos, section.Repo)
+			}
+			assert.Equal(t, tt.wantRepos, repos)
+			assert.Equal(t, tt.wantOwners, d.Owners)
+			assert.Equal(t, tt.wantAuthors, d.Authors)
+			assert.Equal(t, tt.wantTotal, d.Total())
+		})
+	}
+
+	// Failures are listed most recent first
+	d := digest.Build(failures, since, until, []string{"api"}, nil)
+	assert.Empty(t, d.Owners)
+	assert.Equal(t, int64(3), d.Repos[0].Failures[0].RunID)
+	assert.Equal(t, int64(2), d.Repos[0].Failures[1].RunID)
+}
+
+func TestRender(t *testing.T) {
+	until := time.Date(2024, 3, 15, 8, 0, 0, 0, time.UTC)
+	since := until.AddDate(0, 0, -1)
+
+	d := digest.Build([]github.WorkflowFailure{
+		{
+			RunID:      1,
+			Repo:       "api",
+			PRNumber:   42,
+			Workflow:   "<CI>",
+			Conclusion: "failure",
+			Actor:      "alice",
+			StartedAt:  until.Add(-time.Hour),
+			URL:        "https://github.com/acme/api/actions/runs/1",
+			PRURL:      "https://github.com/acme/api/pull/42",
+		},
+		{
+			RunID:      2,
+			Repo:       "api",
+			Workflow:   "Deploy",
+			Conclusion: "failure",
+			Branch:     "main",
+			StartedAt:  until.Add(-2 * time.Hour),
+			URL:        "https://github.com/acme/api/actions/runs/2",
+		},
+	}, since, until, nil, map[string][]string{"api-lead@example.com": {"api"}})
+
+	assert.Equal(t, "Workflow failures Mar 14 - Mar 15: 2 failed run(s) in 1 repositories", d.Subject())
+
+	text := d.Text()
+	assert.Contains(t, text, "api (2)")
+	assert.Contains(t, text, "<CI> failure on PR #42 by alice")
+	assert.Contains(t, text, "https://github.com/acme/api/actions/runs/1")
+	assert.Contains(t, text, "Deploy failure on main at")
+	assert.Contains(t, text, "Failures by owner\n  api-lead@example.com: 2")
+	assert.Contains(t, text, "alice: 1")
+
+	html, err := d.HTML()
+	require.NoError(t, err)
+	assert.Contains(t, html, "<h3>api (2)</h3>")
+	assert.Contains(t, html, "<td>main</td>")
+	assert.Contains(t, html, "<tr><td>api-lead@example.com</td><td align=\"right\">2</td></tr>")
+	assert.Contains(t, html, `<a href="https://github.com/acme/api/actions/runs/1">&lt;CI&gt;</a>`)
+	assert.Contains(t, html, `<a href="https://github.com/acme/api/pull/42">#42</a>`)
+
+	empty := digest.Build(nil, since, until, nil, nil)
+	assert.Contains(t, empty.Text(), "No failed workflow runs.")
+}
@@ -0,0 +1,149 @@
+package digest_test
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sink is a minimal local SMTP server that records the messages it receives
+type sink struct {
+	listener net.Listener
+	messages chan received
+}
+
+type received struct {
+	from string
+	to   []string
+	data string
+}
+
+func newSink(t *testing.T) *sink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	s := &sink{listener: listener, messages: make(chan received, 10)}
+	go s.serve()
+	return s
+}
+
+func (s *sink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *sink) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *sink) handle(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+
+	var msg received
+	_ = tp.PrintfLine("220 localhost ESMTP sink")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250-localhost")
+			_ = tp.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			_ = tp.PrintfLine("235 Authentication successful")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 Go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			s.messages <- msg
+			msg = received{}
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	s := newSink(t)
+
+	cfg := digest.SMTPConfig{
+		Host:     "localhost",
+		Port:     s.port(),
+		Username: "user",
+		Password: "secret",
+		From:     "ci@example.com",
+	}
+	err := digest.Send(cfg, []string{"lead@example.com"}, "Workflow failures", "3 failures", "<p>3 failures</p>")
+	require.NoError(t, err)
+
+	got := <-s.messages
+	assert.Equal(t, "ci@example.com", got.from)
+	assert.Equal(t, []string{"lead@example.com"}, got.to)
+
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(got.data)))
+	require.NoError(t, err)
+	assert.Equal(t, "Workflow failures", msg.Header.Get("Subject"))
+	assert.Equal(t, "lead@example.com", msg.Header.Get("To"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+		parts[part.Header.Get("Content-Type")] = string(content)
+	}
+	assert.Equal(t, map[string]string{
+		"text/plain; charset=utf-8": "3 failures",
+		"text/html; charset=utf-8":  "<p>3 failures</p>",
+	}, parts)
+}
+
+func TestSendRequiresStartTLS(t *testing.T) {
+	s := newSink(t)
+
+	cfg := digest.SMTPConfig{Host: "localhost", Port: s.port(), From: "ci@example.com", StartTLS: true}
+	err := digest.Send(cfg, []string{"lead@example.com"}, "Workflow failures", "text", "<p>html</p>")
+	assert.ErrorContains(t, err, "STARTTLS")
+}
@@ -0,0 +1,181 @@
+package exporter
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/metrics"
+)
+
+const namespace = "gh_workflow_monitor_"
+
+// Exporter polls GitHub and exposes workflow health as Prometheus metrics
+type Exporter struct {
+	client   github.Client
+	days     int
+	registry *metrics.Registry
+
+	failingWorkflows   *metrics.Vec
+	failedRuns         *metrics.Vec
+	scanDuration       *metrics.Vec
+	scanErrors         *metrics.Vec
+	rateLimitRemaining *metrics.Vec
+	lastSuccessfulScan *metrics.Vec
+
+	mu   sync.Mutex
+	seen map[github.FailureKey]time.Time
+}
+
+// New creates an exporter that counts failed runs created within the last days days
+func New(client github.Client, days int) *Exporter {
+	registry := metrics.NewRegistry()
+
+	e := &Exporter{
+		client:   client,
+		days:     days,
+		registry: registry,
+
+		failingWorkflows: registry.NewGauge(namespace+"failing_workflows",
+			"Number of workflows whose latest run on the default branch failed", "repo"),
+		failedRuns: registry.NewCounter(namespace+"failed_runs_total",
+			"Number of failed workflow runs observed", "repo", "conclusion"),
+		scanDuration: registry.NewGauge(namespace+"scan_duration_seconds",
+			"Duration of the last scan"),
+		scanErrors: registry.NewCounter(namespace+"scan_errors_total",
+			"Number of scans that failed"),
+		rateLimitRemaining: registry.NewGauge(namespace+"rate_limit_remaining",
+			"Remaining GitHub API requests in the current rate limit window"),
+		lastSuccessfulScan: registry.NewGauge(namespace+"last_successful_scan_timestamp_seconds",
+			"Unix time of the last successful scan"),
+
+		seen: make(map[github.FailureKey]time.Time),
+	}
+
+	// Export the error counter from the start so that rate() works on the first error
+	e.scanErrors.Add(0)
+
+	return e
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.registry.ServeHTTP(w, r)
+}
+
+// Registry returns the registry holding the exporter's metrics
+func (e *Exporter) Registry() *metrics.Registry {
+	return e.registry
+}
+
+// Observe counts a failed run, ignoring runs that have already been counted
+func (e *Exporter) Observe(failure github.WorkflowFailure) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.seen[failure.Key()]; ok {
+		return
+	}
+	e.seen[failure.Key()] = failure.StartedAt
+
+	e.failedRuns.Add(1, failure.Repo, failure.Conclusion)
+}
+
+// Scan refreshes every metric from the GitHub API
+func (e *Exporter) Scan(ctx context.Context) error {
+	start := time.Now()
+	err := e.scan(ctx)
+	e.scanDuration.Set(time.Since(start).Seconds())
+
+	if err != nil {
+		e.scanErrors.Add(1)
+		return err
+	}
+
+	e.lastSuccessfulScan.Set(float64(time.Now().Unix()))
+	return nil
+}
+
+func (e *Exporter) scan(ctx context.Context) error {
+	streaks, err := e.client.ListBranchStreaks(ctx, github.BranchOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Every repository gets a value, so healthy repositories report zero rather than disappearing
+	failing := make(map[string]int)
+	for _, streak := range streaks {
+		count := failing[streak.Repo]
+		if streak.Failing() {
+			count++
+		}
+		failing[streak.Repo] = count
+	}
+	e.failingWorkflows.Reset()
+	for repo, count := range failing {
+		e.failingWorkflows.Set(float64(count), repo)
+	}
+
+	runs, err := e.client.ListCompletedRuns(ctx, e.days)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if !run.Failed() {
+			continue
+		}
+		e.Observe(github.WorkflowFailure{
+			RunID:      run.ID,
+			Repo:       run.Repo,
+			Workflow:   run.Workflow,
+			Conclusion: run.Conclusion,
+			HeadSHA:    run.HeadSHA,
+			StartedAt:  run.CreatedAt,
+			UpdatedAt:  run.UpdatedAt,
+			URL:        run.URL,
+		})
+	}
+	e.prune()
+
+	limit, err := e.client.GetRateLimit(ctx)
+	if err != nil {
+		return err
+	}
+	e.rateLimitRemaining.Set(float64(limit.Remaining))
+
+	return nil
+}
+
+// prune forgets runs that are too old to be returned by a scan again
+func (e *Exporter) prune() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cutoff := time.Now().AddDate(0, 0, -(e.days + 1))
+	for key, startedAt := range e.seen {
+		if startedAt.Before(cutoff) {
+			delete(e.seen, key)
+		}
+	}
+}
+
+// Run scans immediately and then every interval until the context is cancelled
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Scan(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Scan failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package exporter_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/exporter"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	now := time.Now()
+	streaks := []github.WorkflowStreak{
+		{Repo: "repo1", Workflow: "CI", FailingRuns: []github.RunSummary{{ID: 1, Conclusion: "failure"}}},
+		{Repo: "repo1", Workflow: "Lint"},
+		{Repo: "repo2", Workflow: "CI"},
+	}
+	runs := []github.RunSummary{
+		{ID: 1, Repo: "repo1", Conclusion: "failure", CreatedAt: now},
+		{ID: 2, Repo: "repo1", Conclusion: "timed_out", CreatedAt: now},
+		{ID: 3, Repo: "repo2", Conclusion: "success", CreatedAt: now},
+	}
+
+	client := mocks.NewMockClient(t)
+	client.EXPECT().ListBranchStreaks(mock.Anything, github.BranchOptions{}).Return(streaks, nil)
+	client.EXPECT().ListCompletedRuns(mock.Anything, 1).Return(runs, nil)
+	client.EXPECT().GetRateLimit(mock.Anything).Return(github.RateLimit{Limit: 5000, Remaining: 4321}, nil)
+
+	exp := exporter.New(client, 1)
+
+	// Scanning twice must not double count runs
+	require.NoError(t, exp.Scan(context.Background()))
+	require.NoError(t, exp.Scan(context.Background()))
+
+	var b strings.Builder
+	require.NoError(t, exp.Registry().WriteText(&b))
+	out := b.String()
+
+	assert.Contains(t, out, `gh_workflow_monitor_failing_workflows{repo="repo1"} 1`)
+	assert.Contains(t, out, `gh_workflow_monitor_failing_workflows{repo="repo2"} 0`)
+	assert.Contains(t, out, `gh_workflow_monitor_failed_runs_total{repo="repo1",conclusion="failure"} 1`)
+	assert.Contains(t, out, `gh_workflow_monitor_failed_runs_total{repo="repo1",conclusion="timed_out"} 1`)
+	assert.NotContains(t, out, `conclusion="success"`)
+	assert.Contains(t, out, "gh_workflow_monitor_rate_limit_remaining 4321")
+	assert.Contains(t, out, "\ngh_workflow_monitor_last_successful_scan_timestamp_seconds ")
+	assert.Contains(t, out, "gh_workflow_monitor_scan_duration_seconds ")
+}
+
+func TestScanError(t *testing.T) {
+	client := mocks.NewMockClient(t)
+	client.EXPECT().ListBranchStreaks(mock.Anything, github.BranchOptions{}).Return(nil, fmt.Errorf("mock error"))
+
+	exp := exporter.New(client, 1)
+	assert.Error(t, exp.Scan(context.Background()))
+
+	var b strings.Builder
+	require.NoError(t, exp.Registry().WriteText(&b))
+	assert.Contains(t, b.String(), "gh_workflow_monitor_scan_errors_total 1")
+	assert.NotContains(t, b.String(), "\ngh_workflow_monitor_last_successful_scan_timestamp_seconds ")
+}
@@ -0,0 +1,184 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// AppConfig identifies a GitHub App installation to authenticate as
+type AppConfig struct {
+	AppID int64
+	// InstallationID is looked up from the owner when zero
+	InstallationID int64
+	PrivateKey     *rsa.PrivateKey
+}
+
+// WithApp authenticates as a GitHub App installation instead of with a token
+func WithApp(app AppConfig) Option {
+	return func(o *clientOptions) {
+		o.app = &app
+	}
+}
+
+// ParsePrivateKey parses the PEM encoded private key of a GitHub App
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("error parsing private key: no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("error parsing private key: not an RSA key")
+	}
+	return key, nil
+}
+
+// AppJWT creates the JSON Web Token a GitHub App authenticates with to manage its
+// installations. It is valid for ten minutes, backdated a minute for clock drift.
+func AppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("error signing JWT: %v", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// installationTokenMargin is how long before expiry an installation token is replaced
+const installationTokenMargin = 5 * time.Minute
+
+// AppTransport is an http.RoundTripper that authenticates requests as a GitHub App
+// installation. Installation tokens are created on first use and replaced shortly
+// before they expire.
+type AppTransport struct {
+	app   AppConfig
+	owner string
+	base  http.RoundTripper
+	apps  *github.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewAppTransport creates a transport for the installation of app, looking it up from
+// owner when the installation ID is not configured. Requests, including those that
+// create installation tokens, are sent through base, http.DefaultTransport when nil.
+func NewAppTransport(app AppConfig, owner string, base http.RoundTripper) *AppTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &AppTransport{app: app, owner: owner, base: base}
+	t.apps = github.NewClient(&http.Client{Transport: roundTripperFunc(t.sendAsApp)})
+	return t
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *AppTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.installationToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// installationToken returns the current installation token, creating a new one when it
+// is about to expire
+func (t *AppTransport) installationToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Until(t.expires) > installationTokenMargin {
+		return t.token, nil
+	}
+
+	if t.app.InstallationID == 0 {
+		id, err := t.findInstallation(ctx)
+		if err != nil {
+			return "", err
+		}
+		t.app.InstallationID = id
+	}
+
+	token, _, err := t.apps.Apps.CreateInstallationToken(ctx, t.app.InstallationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating installation token: %v", err)
+	}
+
+	t.token = token.GetToken()
+	t.expires = token.GetExpiresAt().Time
+	return t.token, nil
+}
+
+// findInstallation looks up the app's installation on the owner, which may be an
+// organization or a user
+func (t *AppTransport) findInstallation(ctx context.Context) (int64, error) {
+	installation, resp, err := t.apps.Apps.FindOrganizationInstallation(ctx, t.owner)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+		installation, _, err = t.apps.Apps.FindUserInstallation(ctx, t.owner)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error finding app installation for %s: %v", t.owner, err)
+	}
+
+	return installation.GetID(), nil
+}
+
+// sendAsApp sends a request authenticated as the app itself
+func (t *AppTransport) sendAsApp(req *http.Request) (*http.Response, error) {
+	jwt, err := AppJWT(t.app.AppID, t.app.PrivateKey, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return t.base.RoundTrip(req)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
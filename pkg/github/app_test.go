@@ -0,0 +1,141 @@
+package github_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	for name, data := range map[string][]byte{
+		"pkcs1": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		"pkcs8": pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := github.ParsePrivateKey(data)
+			require.NoError(t, err)
+			assert.True(t, key.Equal(parsed))
+		})
+	}
+
+	_, err = github.ParsePrivateKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestAppJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	token, err := github.AppJWT(42, key, now)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, "42", claims["iss"])
+	assert.Equal(t, float64(now.Unix()-60), claims["iat"])
+	assert.Equal(t, float64(now.Unix()+540), claims["exp"])
+}
+
+// rewriteTransport sends every request to a test server
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (r rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = r.target.Scheme, r.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestAppTransport(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// The first token expires within the refresh margin, the second one does not
+	expiries := []time.Time{time.Now().Add(2 * time.Minute), time.Now().Add(time.Hour)}
+	issued := 0
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		auth := r.Header.Get("Authorization")
+		switch {
+		case r.URL.Path == "/orgs/octocat/installation":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		case r.URL.Path == "/users/octocat/installation":
+			assert.Equal(t, 2, strings.Count(auth, "."), "app requests use a JWT")
+			fmt.Fprint(w, `{"id": 7}`)
+		case r.URL.Path == "/app/installations/7/access_tokens":
+			expires := expiries[issued]
+			issued++
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token": "ghs_%d", "expires_at": %q}`, issued, expires.Format(time.RFC3339))
+		case r.URL.Path == "/rate_limit":
+			fmt.Fprintf(w, `{"resources": {"core": {"limit": 15000, "remaining": 14999}}, "auth": %q}`, auth)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	transport := github.NewAppTransport(github.AppConfig{AppID: 42, PrivateKey: key}, "octocat", rewriteTransport{target})
+	client := &http.Client{Transport: transport}
+
+	get := func() string {
+		resp, err := client.Get("https://api.github.com/rate_limit")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var body struct{ Auth string }
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body.Auth
+	}
+
+	assert.Equal(t, "Bearer ghs_1", get())
+	// The first token is about to expire, so it is replaced without another lookup
+	assert.Equal(t, "Bearer ghs_2", get())
+	assert.Equal(t, "Bearer ghs_2", get())
+
+	assert.Equal(t, []string{
+		"GET /orgs/octocat/installation",
+		"GET /users/octocat/installation",
+		"POST /app/installations/7/access_tokens",
+		"GET /rate_limit",
+		"POST /app/installations/7/access_tokens",
+		"GET /rate_limit",
+		"GET /rate_limit",
+	}, calls)
+}
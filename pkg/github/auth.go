@@ -0,0 +1,87 @@
+package github
+
+import (
+	"net/http"
+	"sync"
+)
+
+// TokenTransport is an http.RoundTripper that authenticates requests with a token. When
+// Refresh is set, a request rejected with 401 Unauthorized is retried once with a fresh
+// token, so that long-running modes survive token rotation.
+type TokenTransport struct {
+	// Base is the transport requests are sent through, http.DefaultTransport when nil
+	Base http.RoundTripper
+	// Refresh returns a new token, e.g. by running the configured token command again
+	Refresh func() (string, error)
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewTokenTransport creates a transport authenticating with token
+func NewTokenTransport(token string, refresh func() (string, error), base http.RoundTripper) *TokenTransport {
+	return &TokenTransport{Base: base, Refresh: refresh, token: token}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *TokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := t.current()
+	resp, err := t.send(req, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || t.Refresh == nil {
+		return resp, err
+	}
+
+	// Requests whose body cannot be replayed are not retried
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	fresh, err := t.renew(token)
+	if err != nil || fresh == token {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return t.send(retry, fresh)
+}
+
+func (t *TokenTransport) current() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.token
+}
+
+// renew replaces the token that was rejected, unless a concurrent request already did
+func (t *TokenTransport) renew(rejected string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != rejected {
+		return t.token, nil
+	}
+
+	fresh, err := t.Refresh()
+	if err != nil {
+		return "", err
+	}
+	t.token = fresh
+	return fresh, nil
+}
+
+func (t *TokenTransport) send(req *http.Request, token string) (*http.Response, error) {
+	// RoundTrippers must not modify the request they are given
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,91 @@
+package github_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenTransport(t *testing.T) {
+	valid := "fresh"
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Authorization") != "Bearer "+valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		refresh     func() (string, error)
+		wantStatus  int
+		wantBodies  []string
+		wantRefresh int
+	}{
+		{
+			name:        "retries with a fresh token",
+			refresh:     func() (string, error) { return "fresh", nil },
+			wantStatus:  http.StatusOK,
+			wantBodies:  []string{"payload", "payload"},
+			wantRefresh: 1,
+		},
+		{
+			name:        "same token is not retried",
+			refresh:     func() (string, error) { return "stale", nil },
+			wantStatus:  http.StatusUnauthorized,
+			wantBodies:  []string{"payload"},
+			wantRefresh: 1,
+		},
+		{
+			name:        "refresh error returns the rejection",
+			refresh:     func() (string, error) { return "", fmt.Errorf("vault is sealed") },
+			wantStatus:  http.StatusUnauthorized,
+			wantBodies:  []string{"payload"},
+			wantRefresh: 1,
+		},
+		{
+			name:       "without refresh",
+			wantStatus: http.StatusUnauthorized,
+			wantBodies: []string{"payload"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies = nil
+			refreshed := 0
+			var refresh func() (string, error)
+			if tt.refresh != nil {
+				refresh = func() (string, error) {
+					refreshed++
+					return tt.refresh()
+				}
+			}
+
+			client := &http.Client{Transport: github.NewTokenTransport("stale", refresh, nil)}
+			req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+			require.NoError(t, err)
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantBodies, bodies)
+			assert.Equal(t, tt.wantRefresh, refreshed)
+			assert.Empty(t, req.Header.Get("Authorization"))
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// RunSummary is a condensed view of a single completed workflow run
+type RunSummary struct {
+	ID         int64
+	Number     int
+	Repo       string
+	Branch     string
+	WorkflowID int64
+	Workflow   string
+	Conclusion string
+	HeadSHA    string
+	// Actor is the login of the user who triggered the run
+	Actor string
+	// PRNumber is the number of the PR the run belongs to, or zero for branch runs
+	PRNumber  int
+	PRURL     string
+	CreatedAt time.Time
+	StartedAt time.Time
+	UpdatedAt time.Time
+	URL       string
+}
+
+// Failed reports whether the run counts as a failure
+func (r RunSummary) Failed() bool {
+	switch r.Conclusion {
+	case "failure", "timed_out", "startup_failure":
+		return true
+	}
+	return false
+}
+
+// Failure converts a failed run to a WorkflowFailure
+func (r RunSummary) Failure() WorkflowFailure {
+	return WorkflowFailure{
+		RunID:      r.ID,
+		Repo:       r.Repo,
+		PRNumber:   r.PRNumber,
+		Workflow:   r.Workflow,
+		Conclusion: r.Conclusion,
+		HeadSHA:    r.HeadSHA,
+		Branch:     r.Branch,
+		Actor:      r.Actor,
+		StartedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+		URL:        r.URL,
+		PRURL:      r.PRURL,
+	}
+}
+
+// Succeeded reports whether the run counts as a success
+func (r RunSummary) Succeeded() bool {
+	return r.Conclusion == "success"
+}
+
+// Duration returns how long the run took from starting to its last update
+func (r RunSummary) Duration() time.Duration {
+	if r.StartedAt.IsZero() || r.UpdatedAt.Before(r.StartedAt) {
+		return 0
+	}
+	return r.UpdatedAt.Sub(r.StartedAt)
+}
+
+// WorkflowStreak describes the current state of a workflow on a branch
+type WorkflowStreak struct {
+	Repo       string
+	RepoURL    string
+	Branch     string
+	Workflow   string
+	WorkflowID int64
+	// FailingRuns holds the consecutive failed runs since the last success, newest first
+	FailingRuns []RunSummary
+	LastSuccess *RunSummary
+}
+
+// Failing reports whether the latest conclusive run of the workflow failed
+func (s WorkflowStreak) Failing() bool {
+	return len(s.FailingRuns) > 0
+}
+
+// FirstFailure returns the oldest run of the current failure streak
+func (s WorkflowStreak) FirstFailure() *RunSummary {
+	if len(s.FailingRuns) == 0 {
+		return nil
+	}
+	return &s.FailingRuns[len(s.FailingRuns)-1]
+}
+
+// GroupStreaks groups runs by workflow and computes the current streak of each.
+// Runs that neither failed nor succeeded (e.g. cancelled or skipped) are ignored.
+func GroupStreaks(repo, repoURL, branch string, runs []RunSummary) []WorkflowStreak {
+	sorted := make([]RunSummary, len(runs))
+	copy(sorted, runs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	var streaks []*WorkflowStreak
+	byWorkflow := make(map[int64]*WorkflowStreak)
+	done := make(map[int64]bool)
+
+	for _, run := range sorted {
+		if !run.Failed() && !run.Succeeded() {
+			continue
+		}
+
+		streak, ok := byWorkflow[run.WorkflowID]
+		if !ok {
+			streak = &WorkflowStreak{
+				Repo:       repo,
+				RepoURL:    repoURL,
+				Branch:     branch,
+				Workflow:   run.Workflow,
+				WorkflowID: run.WorkflowID,
+			}
+			byWorkflow[run.WorkflowID] = streak
+			streaks = append(streaks, streak)
+		}
+
+		if done[run.WorkflowID] {
+			continue
+		}
+
+		if run.Succeeded() {
+			success := run
+			streak.LastSuccess = &success
+			done[run.WorkflowID] = true
+			continue
+		}
+
+		streak.FailingRuns = append(streak.FailingRuns, run)
+	}
+
+	result := make([]WorkflowStreak, 0, len(streaks))
+	for _, streak := range streaks {
+		result = append(result, *streak)
+	}
+	return result
+}
+
+// BranchOptions selects which branches are inspected in addition to each repository's default branch
+type BranchOptions struct {
+	// Branches lists extra branch names to inspect wherever they exist
+	Branches []string
+	// Protected includes every protected branch of each repository
+	Protected bool
+}
+
+// ListBranchStreaks retrieves the current streak of every workflow on the selected branches of each repository
+func (g *GitHubClient) ListBranchStreaks(ctx context.Context, opts BranchOptions) ([]WorkflowStreak, error) {
+	allRepos, err := g.listRepos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var streaks []WorkflowStreak
+	for _, repo := range allRepos {
+		if repo.GetArchived() || repo.GetDefaultBranch() == "" {
+			continue
+		}
+
+		branches, err := g.selectBranches(ctx, repo, opts)
+		if err != nil {
+			continue
+		}
+
+		workflows, err := g.listActiveWorkflows(ctx, repo.GetName())
+		if err != nil {
+			continue
+		}
+
+		for _, branch := range branches {
+			runs, err := g.listBranchRuns(ctx, repo.GetName(), branch, workflows)
+			if err != nil {
+				continue
+			}
+
+			streaks = append(streaks, GroupStreaks(repo.GetName(), repo.GetHTMLURL(), branch, runs)...)
+		}
+	}
+
+	return streaks, nil
+}
+
+// selectBranches returns the de-duplicated list of branches to inspect for a repository
+func (g *GitHubClient) selectBranches(ctx context.Context, repo *github.Repository, opts BranchOptions) ([]string, error) {
+	branches := []string{repo.GetDefaultBranch()}
+	seen := map[string]bool{repo.GetDefaultBranch(): true}
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			branches = append(branches, name)
+		}
+	}
+
+	for _, name := range opts.Branches {
+		add(name)
+	}
+
+	if opts.Protected {
+		listOpts := &github.BranchListOptions{
+			Protected: github.Bool(true),
+			ListOptions: github.ListOptions{
+				PerPage: 100,
+			},
+		}
+
+		for {
+			protected, resp, err := g.client.Repositories.ListBranches(ctx, g.owner, repo.GetName(), listOpts)
+			if err != nil {
+				return nil, fmt.Errorf("error listing protected branches for %s: %v", repo.GetName(), err)
+			}
+
+			for _, branch := range protected {
+				add(branch.GetName())
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			listOpts.Page = resp.NextPage
+		}
+	}
+
+	return branches, nil
+}
+
+// BranchHealth summarises the workflow streaks of a single branch
+type BranchHealth struct {
+	Repo    string
+	Branch  string
+	Streaks []WorkflowStreak
+}
+
+// Failing returns the streaks of workflows that are currently failing
+func (b BranchHealth) Failing() []WorkflowStreak {
+	var failing []WorkflowStreak
+	for _, streak := range b.Streaks {
+		if streak.Failing() {
+			failing = append(failing, streak)
+		}
+	}
+	return failing
+}
+
+// RedSince returns when the oldest current failure on the branch started, or the zero time if the branch is green
+func (b BranchHealth) RedSince() time.Time {
+	var since time.Time
+	for _, streak := range b.Failing() {
+		first := streak.FirstFailure()
+		if since.IsZero() || first.CreatedAt.Before(since) {
+			since = first.CreatedAt
+		}
+	}
+	return since
+}
+
+// GroupBranches groups workflow streaks by repository and branch, preserving their order
+func GroupBranches(streaks []WorkflowStreak) []BranchHealth {
+	var branches []BranchHealth
+	index := make(map[string]int)
+
+	for _, streak := range streaks {
+		key := streak.Repo + "\x00" + streak.Branch
+		i, ok := index[key]
+		if !ok {
+			i = len(branches)
+			index[key] = i
+			branches = append(branches, BranchHealth{Repo: streak.Repo, Branch: streak.Branch})
+		}
+		branches[i].Streaks = append(branches[i].Streaks, streak)
+	}
+
+	return branches
+}
+
+// listBranchRuns retrieves the most recent completed workflow runs on a branch.
+// Workflows without a conclusive run in the latest page are looked up individually,
+// so that a workflow that runs rarely still has a current streak.
+func (g *GitHubClient) listBranchRuns(ctx context.Context, repo, branch string, workflowIDs []int64) ([]RunSummary, error) {
+	opts := &github.ListWorkflowRunsOptions{
+		Branch: branch,
+		Status: "completed",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	runs, _, err := g.client.Actions.ListRepositoryWorkflowRuns(ctx, g.owner, repo, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error getting workflow runs for %s: %v", repo, err)
+	}
+
+	summaries := make([]RunSummary, 0, len(runs.WorkflowRuns))
+	conclusive := make(map[int64]bool)
+	for _, run := range runs.WorkflowRuns {
+		summary := newRunSummary(run)
+		summaries = append(summaries, summary)
+		if summary.Failed() || summary.Succeeded() {
+			conclusive[summary.WorkflowID] = true
+		}
+	}
+
+	for _, id := range workflowIDs {
+		if conclusive[id] {
+			continue
+		}
+
+		older, err := g.walkToLastSuccess(ctx, repo, branch, id)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, older...)
+	}
+
+	return dedupeRuns(summaries), nil
+}
+
+// listActiveWorkflows returns the IDs of the active workflows of a repository
+func (g *GitHubClient) listActiveWorkflows(ctx context.Context, repo string) ([]int64, error) {
+	var ids []int64
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		workflows, resp, err := g.client.Actions.ListWorkflows(ctx, g.owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error listing workflows: %v", err)
+		}
+
+		for _, wf := range workflows.Workflows {
+			if wf.GetState() == "active" {
+				ids = append(ids, wf.GetID())
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return ids, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// dedupeRuns drops repeated runs, keeping the first occurrence of each
+func dedupeRuns(runs []RunSummary) []RunSummary {
+	seen := make(map[int64]bool, len(runs))
+	result := runs[:0]
+	for _, run := range runs {
+		if seen[run.ID] {
+			continue
+		}
+		seen[run.ID] = true
+		result = append(result, run)
+	}
+	return result
+}
+
+// newRunSummary converts an API workflow run to a RunSummary
+func newRunSummary(run *github.WorkflowRun) RunSummary {
+	prNumber := 0
+	if len(run.PullRequests) > 0 {
+		prNumber = run.PullRequests[0].GetNumber()
+	}
+
+	return RunSummary{
+		ID:         run.GetID(),
+		Number:     run.GetRunNumber(),
+		Repo:       run.GetRepository().GetName(),
+		Branch:     run.GetHeadBranch(),
+		WorkflowID: run.GetWorkflowID(),
+		Workflow:   run.GetName(),
+		Conclusion: run.GetConclusion(),
+		HeadSHA:    run.GetHeadSHA(),
+		Actor:      run.GetActor().GetLogin(),
+		PRNumber:   prNumber,
+		CreatedAt:  run.GetCreatedAt().Time,
+		StartedAt:  run.GetRunStartedAt().Time,
+		UpdatedAt:  run.GetUpdatedAt().Time,
+		URL:        run.GetHTMLURL(),
+	}
+}
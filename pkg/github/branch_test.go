@@ -0,0 +1,170 @@
+package github_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupStreaks(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := func(id int64, workflowID int64, conclusion string, hoursAgo int) github.RunSummary {
+		return github.RunSummary{
+			ID:         id,
+			Number:     int(id),
+			WorkflowID: workflowID,
+			Workflow:   map[int64]string{1: "CI", 2: "Deploy"}[workflowID],
+			Conclusion: conclusion,
+			HeadSHA:    "sha",
+			CreatedAt:  base.Add(-time.Duration(hoursAgo) * time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name            string
+		runs            []github.RunSummary
+		wantStreaks     int
+		wantFailing     map[string]int
+		wantLastSuccess map[string]int64
+		wantFirstBad    map[string]int64
+	}{
+		{
+			name: "failing after success",
+			runs: []github.RunSummary{
+				run(4, 1, "failure", 1),
+				run(3, 1, "cancelled", 2),
+				run(2, 1, "timed_out", 3),
+				run(1, 1, "success", 4),
+				run(0, 1, "failure", 5),
+			},
+			wantStreaks:     1,
+			wantFailing:     map[string]int{"CI": 2},
+			wantLastSuccess: map[string]int64{"CI": 1},
+			wantFirstBad:    map[string]int64{"CI": 2},
+		},
+		{
+			name: "mixed workflows in any order",
+			runs: []github.RunSummary{
+				run(1, 2, "success", 3),
+				run(2, 1, "failure", 2),
+				run(3, 2, "success", 1),
+			},
+			wantStreaks:     2,
+			wantFailing:     map[string]int{"CI": 1, "Deploy": 0},
+			wantLastSuccess: map[string]int64{"Deploy": 3},
+			wantFirstBad:    map[string]int64{"CI": 2},
+		},
+		{
+			name: "only inconclusive runs",
+			runs: []github.RunSummary{
+				run(1, 1, "skipped", 1),
+			},
+			wantStreaks: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streaks := github.GroupStreaks("repo", "https://github.com/owner/repo", "main", tt.runs)
+
+			assert.Len(t, streaks, tt.wantStreaks)
+			for _, streak := range streaks {
+				assert.Equal(t, "repo", streak.Repo)
+				assert.Equal(t, "main", streak.Branch)
+				assert.Equal(t, tt.wantFailing[streak.Workflow], len(streak.FailingRuns))
+
+				if id, ok := tt.wantLastSuccess[streak.Workflow]; ok {
+					if assert.NotNil(t, streak.LastSuccess) {
+						assert.Equal(t, id, streak.LastSuccess.ID)
+					}
+				} else {
+					assert.Nil(t, streak.LastSuccess)
+				}
+
+				if id, ok := tt.wantFirstBad[streak.Workflow]; ok {
+					if assert.NotNil(t, streak.FirstFailure()) {
+						assert.Equal(t, id, streak.FirstFailure().ID)
+					}
+				} else {
+					assert.Nil(t, streak.FirstFailure())
+				}
+			}
+		})
+	}
+}
+
+func TestGroupBranches(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	streaks := []github.WorkflowStreak{
+		{Repo: "repo1", Branch: "main", Workflow: "CI", FailingRuns: []github.RunSummary{{CreatedAt: newer}}},
+		{Repo: "repo1", Branch: "main", Workflow: "Lint"},
+		{Repo: "repo1", Branch: "release", Workflow: "CI"},
+		{Repo: "repo1", Branch: "main", Workflow: "Deploy", FailingRuns: []github.RunSummary{{CreatedAt: newer}, {CreatedAt: older}}},
+	}
+
+	branches := github.GroupBranches(streaks)
+
+	if assert.Len(t, branches, 2) {
+		assert.Equal(t, "main", branches[0].Branch)
+		assert.Len(t, branches[0].Streaks, 3)
+		assert.Len(t, branches[0].Failing(), 2)
+		assert.Equal(t, older, branches[0].RedSince())
+
+		assert.Equal(t, "release", branches[1].Branch)
+		assert.Empty(t, branches[1].Failing())
+		assert.True(t, branches[1].RedSince().IsZero())
+	}
+}
+
+func TestListBranchStreaks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/orgs/acme/repos":
+			fmt.Fprint(w, `[{"name": "api", "default_branch": "main"}]`)
+		case "/api/v3/repos/acme/api/actions/workflows":
+			fmt.Fprint(w, `{"total_count": 3, "workflows": [
+				{"id": 1, "name": "CI", "state": "active"},
+				{"id": 2, "name": "Nightly", "state": "active"},
+				{"id": 3, "name": "Old", "state": "disabled_manually"}
+			]}`)
+		case "/api/v3/repos/acme/api/actions/runs":
+			assert.Equal(t, "main", r.URL.Query().Get("branch"))
+			fmt.Fprint(w, `{"total_count": 200, "workflow_runs": [
+				{"id": 10, "workflow_id": 1, "name": "CI", "conclusion": "failure", "created_at": "2024-01-02T00:00:00Z"},
+				{"id": 9, "workflow_id": 1, "name": "CI", "conclusion": "success", "created_at": "2024-01-01T00:00:00Z"}
+			]}`)
+		case "/api/v3/repos/acme/api/actions/workflows/2/runs":
+			// The nightly run fell off the first page of branch runs
+			fmt.Fprint(w, `{"total_count": 1, "workflow_runs": [
+				{"id": 5, "workflow_id": 2, "name": "Nightly", "conclusion": "success", "created_at": "2023-12-01T00:00:00Z"}
+			]}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := github.NewClient("token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+	require.NoError(t, err)
+
+	streaks, err := client.ListBranchStreaks(context.Background(), github.BranchOptions{})
+	require.NoError(t, err)
+	require.Len(t, streaks, 2)
+
+	assert.Equal(t, "CI", streaks[0].Workflow)
+	assert.True(t, streaks[0].Failing())
+	assert.Equal(t, "Nightly", streaks[1].Workflow)
+	assert.False(t, streaks[1].Failing())
+	require.NotNil(t, streaks[1].LastSuccess)
+	assert.Equal(t, int64(5), streaks[1].LastSuccess.ID)
+}
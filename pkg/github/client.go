@@ -3,71 +3,351 @@ package github
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v60/github"
-	"golang.org/x/oauth2"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/httpcache"
 )
 
 // WorkflowFailure represents a failed workflow run
 type WorkflowFailure struct {
-	Repo      string
-	PRNumber  int
-	Workflow  string
+	// RunID is zero for failures of check suites created by other apps
+	RunID int64
+	// CheckSuiteID is set instead of RunID for failures of check suites
+	CheckSuiteID int64
+	Repo         string
+	PRNumber     int
+	Workflow     string
+	Conclusion   string
+	HeadSHA      string
+	// Branch is the head branch of the run
+	Branch string
+	// Actor is the login of the user who triggered the run
+	Actor     string
 	StartedAt time.Time
+	UpdatedAt time.Time
 	URL       string
 	PRURL     string
+	// PRAuthor is the login of the user who opened the PR
+	PRAuthor string
+	// Reviewers are the logins of the users whose review the PR requests
+	Reviewers []string
+	// ReviewTeams are the slugs of the teams whose review the PR requests
+	ReviewTeams []string
+	Jobs        []JobFailure
+}
+
+// FailureKey identifies a failure. Workflow runs and check suites are numbered
+// independently, so their IDs are kept apart.
+type FailureKey struct {
+	RunID        int64
+	CheckSuiteID int64
+}
+
+// Key returns the key identifying the failure
+func (f WorkflowFailure) Key() FailureKey {
+	return FailureKey{RunID: f.RunID, CheckSuiteID: f.CheckSuiteID}
+}
+
+// SetPeople records the author and requested reviewers of the failure's PR
+func (f *WorkflowFailure) SetPeople(people PullRequestPeople) {
+	f.PRAuthor = people.Author
+	f.Reviewers = people.Reviewers
+	f.ReviewTeams = people.Teams
+}
+
+// Involves reports whether the user opened the failure's PR or their review is requested,
+// directly or through one of the given teams the user belongs to
+func (f WorkflowFailure) Involves(login string, teams ...string) bool {
+	if login == "" {
+		return false
+	}
+	if strings.EqualFold(f.PRAuthor, login) {
+		return true
+	}
+	for _, reviewer := range f.Reviewers {
+		if strings.EqualFold(reviewer, login) {
+			return true
+		}
+	}
+	for _, requested := range f.ReviewTeams {
+		for _, team := range teams {
+			if strings.EqualFold(requested, team) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PullRequestPeople are the users involved in a pull request
+type PullRequestPeople struct {
+	Author string
+	// Reviewers are the logins of the users whose review is requested
+	Reviewers []string
+	// Teams are the slugs of the teams whose review is requested
+	Teams []string
+}
+
+// JobFailure represents a failed job within a workflow run
+type JobFailure struct {
+	Name string
+	URL  string
 }
 
 // Client defines the interface for GitHub operations
 type Client interface {
-	GetFailedWorkflows(ctx context.Context, prNumber string, repo string) error
+	GetFailedWorkflows(ctx context.Context, prNumber string, repo string) ([]WorkflowFailure, error)
+	FindPullRequest(ctx context.Context, repo, headOwner, branch string) (int, error)
+	CommentFailureSummary(ctx context.Context, prNumber string, repo string, failures []WorkflowFailure) error
 	ListAllFailedWorkflows(ctx context.Context, days int) (map[string][]WorkflowFailure, error)
+	SyncRuns(ctx context.Context, days int, watermarks map[string]Watermark) ([]RunSummary, map[string]Watermark, error)
+	ListCompletedRuns(ctx context.Context, days int) ([]RunSummary, error)
+	ListBranchStreaks(ctx context.Context, opts BranchOptions) ([]WorkflowStreak, error)
+	GetWorkflowStreak(ctx context.Context, repo, branch, workflow string) (WorkflowStreak, error)
+	FindCulprit(ctx context.Context, streak WorkflowStreak) (*Culprit, error)
+	TrackFailureIssues(ctx context.Context, streaks []WorkflowStreak, opts IssueOptions) ([]IssueAction, error)
+	GetRateLimit(ctx context.Context) (RateLimit, error)
+	CurrentUser(ctx context.Context) (string, error)
+	GetPullRequestPeople(ctx context.Context, repo string, number int) (PullRequestPeople, error)
+	IsTeamMember(ctx context.Context, team, login string) (bool, error)
+	Diagnose(ctx context.Context) []Diagnosis
 }
 
 // GitHubClient implements the Client interface
 type GitHubClient struct {
-	client *github.Client
-	owner  string
+	client  *github.Client
+	owner   string
+	include []string
+	exclude []string
+	// app is set when authenticating as a GitHub App installation
+	app bool
 }
 
-// NewClient creates a new GitHub client
-func NewClient(token, owner string) Client {
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
+// Option configures optional behaviour of the GitHub client
+type Option func(*clientOptions)
+
+type clientOptions struct {
+	cache   *httpcache.Transport
+	include []string
+	exclude []string
+	refresh func() (string, error)
+	app     *AppConfig
+	baseURL string
+}
+
+// WithCache caches API responses in cache and revalidates them with conditional requests.
+// The caller owns cache and flushes its statistics when done.
+func WithCache(cache *httpcache.Transport) Option {
+	return func(o *clientOptions) {
+		o.cache = cache
+	}
+}
+
+// WithRepoFilter restricts organization-wide commands to repositories matching one of the
+// include patterns (all repositories when empty) and none of the exclude patterns.
+// Patterns use path.Match syntax, e.g. "api-*".
+func WithRepoFilter(include, exclude []string) Option {
+	return func(o *clientOptions) {
+		o.include = include
+		o.exclude = exclude
+	}
+}
+
+// WithTokenRefresh obtains a new token from refresh whenever the API rejects the current
+// one, e.g. because a token from a secrets manager has been rotated
+func WithTokenRefresh(refresh func() (string, error)) Option {
+	return func(o *clientOptions) {
+		o.refresh = refresh
+	}
+}
+
+// WithBaseURL targets a GitHub Enterprise Server API, e.g. https://ghe.example.com/api/v3,
+// instead of api.github.com
+func WithBaseURL(baseURL string) Option {
+	return func(o *clientOptions) {
+		o.baseURL = baseURL
+	}
+}
+
+// NewClient creates a new GitHub client. token is ignored when authenticating as an app.
+func NewClient(token, owner string, opts ...Option) (Client, error) {
+	var options clientOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var base http.RoundTripper
+	if options.cache != nil {
+		// The token transport wraps the cache, so cached requests are still authenticated
+		base = options.cache
+	}
+
+	var transport http.RoundTripper = NewTokenTransport(token, options.refresh, base)
+	if options.app != nil {
+		app := NewAppTransport(*options.app, owner, base)
+		apps, err := withBaseURL(app.apps, options.baseURL)
+		if err != nil {
+			return nil, err
+		}
+		app.apps = apps
+		transport = app
+	}
+
+	client, err := withBaseURL(github.NewClient(&http.Client{Transport: transport}), options.baseURL)
+	if err != nil {
+		return nil, err
+	}
 
 	return &GitHubClient{
-		client: github.NewClient(tc),
-		owner:  owner,
+		client:  client,
+		owner:   owner,
+		include: options.include,
+		exclude: options.exclude,
+		app:     options.app != nil,
+	}, nil
+}
+
+// withBaseURL points a client at a GitHub Enterprise Server API. Clients are returned
+// unchanged for an empty base URL or api.github.com.
+func withBaseURL(client *github.Client, baseURL string) (*github.Client, error) {
+	if baseURL == "" || strings.TrimSuffix(baseURL, "/") == "https://api.github.com" {
+		return client, nil
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("invalid base URL %q: expected e.g. https://ghe.example.com/api/v3", baseURL)
 	}
+
+	// Uploads are served from /api/uploads next to /api/v3
+	uploadURL := strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/v3")
+	return client.WithEnterpriseURLs(baseURL, uploadURL)
 }
 
-// GetFailedWorkflows retrieves failed workflows for a specific PR
-func (g *GitHubClient) GetFailedWorkflows(ctx context.Context, prNumber string, repo string) error {
+// FindPullRequest returns the number of the open pull request whose head is the given
+// branch. headOwner owns the repository the branch was pushed to, e.g. a fork, and
+// defaults to the configured owner. When no pull request has that head, the heads of all
+// open pull requests are searched for the branch, so branches of other forks are found too.
+func (g *GitHubClient) FindPullRequest(ctx context.Context, repo, headOwner, branch string) (int, error) {
+	if headOwner == "" {
+		headOwner = g.owner
+	}
+
+	prs, _, err := g.client.PullRequests.List(ctx, g.owner, repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  headOwner + ":" + branch,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error listing pull requests: %v", err)
+	}
+	if len(prs) > 0 {
+		return prs[0].GetNumber(), nil
+	}
+
+	var matches []int
+	opts := &github.PullRequestListOptions{
+		State: "open",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	for {
+		prs, resp, err := g.client.PullRequests.List(ctx, g.owner, repo, opts)
+		if err != nil {
+			return 0, fmt.Errorf("error listing pull requests: %v", err)
+		}
+
+		for _, pr := range prs {
+			if pr.GetHead().GetRef() == branch {
+				matches = append(matches, pr.GetNumber())
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("no open pull request found for branch %s of %s/%s", branch, g.owner, repo)
+	case 1:
+		return matches[0], nil
+	default:
+		return 0, fmt.Errorf("branch %s is the head of several open pull requests in %s/%s %v: give the pull request number", branch, g.owner, repo, matches)
+	}
+}
+
+// CurrentUser returns the login of the authenticated user
+func (g *GitHubClient) CurrentUser(ctx context.Context) (string, error) {
+	user, _, err := g.client.Users.Get(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("error getting authenticated user: %v", err)
+	}
+	return user.GetLogin(), nil
+}
+
+// GetPullRequestPeople returns the author and requested reviewers of a pull request
+func (g *GitHubClient) GetPullRequestPeople(ctx context.Context, repo string, number int) (PullRequestPeople, error) {
+	pr, _, err := g.client.PullRequests.Get(ctx, g.owner, repo, number)
+	if err != nil {
+		return PullRequestPeople{}, fmt.Errorf("error getting PR %s#%d: %v", repo, number, err)
+	}
+	return prPeople(pr), nil
+}
+
+// IsTeamMember reports whether the user is an active member of a team of the owner
+func (g *GitHubClient) IsTeamMember(ctx context.Context, team, login string) (bool, error) {
+	membership, resp, err := g.client.Teams.GetTeamMembershipBySlug(ctx, g.owner, team, login)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("error getting membership of %s in team %s: %v", login, team, err)
+	}
+	return membership.GetState() == "active", nil
+}
+
+// prPeople returns the author and the requested reviewers of a pull request
+func prPeople(pr *github.PullRequest) PullRequestPeople {
+	people := PullRequestPeople{Author: pr.GetUser().GetLogin()}
+	for _, user := range pr.RequestedReviewers {
+		people.Reviewers = append(people.Reviewers, user.GetLogin())
+	}
+	for _, team := range pr.RequestedTeams {
+		people.Teams = append(people.Teams, team.GetSlug())
+	}
+	return people
+}
+
+// GetFailedWorkflows retrieves the workflows of a specific PR whose latest run failed
+func (g *GitHubClient) GetFailedWorkflows(ctx context.Context, prNumber string, repo string) ([]WorkflowFailure, error) {
 	if repo == "" {
-		return fmt.Errorf("repository name is required")
+		return nil, fmt.Errorf("repository name is required")
 	}
 
-	// Convert PR number to int
-	prNum, err := strconv.Atoi(prNumber)
+	prNum, err := parsePRNumber(prNumber)
 	if err != nil {
-		return fmt.Errorf("invalid PR number: %v", err)
+		return nil, err
 	}
 
 	// Get PR details
 	pr, _, err := g.client.PullRequests.Get(ctx, g.owner, repo, prNum)
 	if err != nil {
-		return fmt.Errorf("error getting PR: %v", err)
+		return nil, fmt.Errorf("error getting PR: %v", err)
 	}
 
-	// Get workflow runs for the PR
+	// Get the most recent completed workflow runs for the PR, newest first
 	opts := &github.ListWorkflowRunsOptions{
-		Branch: *pr.Head.Ref,
-		Status: "failure",
+		Branch: pr.GetHead().GetRef(),
+		Status: "completed",
 		ListOptions: github.ListOptions{
 			PerPage: 100,
 		},
@@ -75,19 +355,106 @@ func (g *GitHubClient) GetFailedWorkflows(ctx context.Context, prNumber string,
 
 	runs, _, err := g.client.Actions.ListRepositoryWorkflowRuns(ctx, g.owner, repo, opts)
 	if err != nil {
-		return fmt.Errorf("error getting workflow runs: %v", err)
+		return nil, fmt.Errorf("error getting workflow runs: %v", err)
+	}
+
+	people := prPeople(pr)
+	var failures []WorkflowFailure
+	latest := make(map[int64]bool)
+	for _, run := range runs.WorkflowRuns {
+		// Only the latest run of each workflow counts, so failures that have since been
+		// fixed are not reported
+		if latest[run.GetWorkflowID()] {
+			continue
+		}
+		latest[run.GetWorkflowID()] = true
+		if !newRunSummary(run).Failed() {
+			continue
+		}
+
+		jobs, err := g.listFailedJobs(ctx, repo, run.GetID())
+		if err != nil {
+			return nil, err
+		}
+
+		failure := WorkflowFailure{
+			RunID:      run.GetID(),
+			Repo:       repo,
+			PRNumber:   prNum,
+			Workflow:   run.GetName(),
+			Conclusion: run.GetConclusion(),
+			HeadSHA:    run.GetHeadSHA(),
+			Branch:     run.GetHeadBranch(),
+			Actor:      run.GetActor().GetLogin(),
+			StartedAt:  run.GetCreatedAt().Time,
+			UpdatedAt:  run.GetUpdatedAt().Time,
+			URL:        run.GetHTMLURL(),
+			PRURL:      pr.GetHTMLURL(),
+			Jobs:       jobs,
+		}
+		failure.SetPeople(people)
+		failures = append(failures, failure)
+	}
+
+	return failures, nil
+}
+
+// listFailedJobs retrieves the failed jobs from the latest attempt of a workflow run
+func (g *GitHubClient) listFailedJobs(ctx context.Context, repo string, runID int64) ([]JobFailure, error) {
+	opts := &github.ListWorkflowJobsOptions{
+		Filter: "latest",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	jobs, _, err := g.client.Actions.ListWorkflowJobs(ctx, g.owner, repo, runID, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error getting jobs for workflow run %d: %v", runID, err)
 	}
 
-	if len(runs.WorkflowRuns) == 0 {
-		return nil
+	var failed []JobFailure
+	for _, job := range jobs.Jobs {
+		if job.GetConclusion() != "failure" {
+			continue
+		}
+		failed = append(failed, JobFailure{
+			Name: job.GetName(),
+			URL:  job.GetHTMLURL(),
+		})
 	}
 
-	return nil
+	return failed, nil
+}
+
+// parsePRNumber converts a PR number argument to an int
+func parsePRNumber(prNumber string) (int, error) {
+	prNum, err := strconv.Atoi(prNumber)
+	if err != nil {
+		return 0, fmt.Errorf("invalid PR number: %v", err)
+	}
+	return prNum, nil
 }
 
 // ListAllFailedWorkflows retrieves all failed workflows across repositories
 func (g *GitHubClient) ListAllFailedWorkflows(ctx context.Context, days int) (map[string][]WorkflowFailure, error) {
-	// List all repositories for the organization with pagination
+	runs, _, err := g.SyncRuns(ctx, days, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make(map[string][]WorkflowFailure)
+	for _, run := range runs {
+		if run.Failed() && run.PRURL != "" {
+			failures[run.PRURL] = append(failures[run.PRURL], run.Failure())
+		}
+	}
+
+	return failures, nil
+}
+
+// listRepos lists all repositories for the organization with pagination
+func (g *GitHubClient) listRepos(ctx context.Context) ([]*github.Repository, error) {
 	var allRepos []*github.Repository
 	listOpts := &github.RepositoryListByOrgOptions{
 		Type:      "all",
@@ -108,7 +475,11 @@ func (g *GitHubClient) ListAllFailedWorkflows(ctx context.Context, days int) (ma
 			return nil, fmt.Errorf("error listing repositories: %v", err)
 		}
 
-		allRepos = append(allRepos, repos...)
+		for _, repo := range repos {
+			if g.matchesFilter(repo.GetName()) {
+				allRepos = append(allRepos, repo)
+			}
+		}
 
 		if resp.NextPage == 0 {
 			break
@@ -120,44 +491,23 @@ func (g *GitHubClient) ListAllFailedWorkflows(ctx context.Context, days int) (ma
 		return nil, fmt.Errorf("no repositories found for organization %s", g.owner)
 	}
 
-	cutoffTime := time.Now().AddDate(0, 0, -days)
-	failures := make(map[string][]WorkflowFailure)
-
-	for _, repo := range allRepos {
-		opts := &github.ListWorkflowRunsOptions{
-			Status: "failure",
-			ListOptions: github.ListOptions{
-				PerPage: 100,
-			},
-		}
+	return allRepos, nil
+}
 
-		runs, _, err := g.client.Actions.ListRepositoryWorkflowRuns(ctx, g.owner, *repo.Name, opts)
-		if err != nil {
-			continue
+// matchesFilter reports whether a repository passes the include and exclude patterns
+func (g *GitHubClient) matchesFilter(name string) bool {
+	for _, pattern := range g.exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false
 		}
-
-		for _, run := range runs.WorkflowRuns {
-			if run.CreatedAt.Before(cutoffTime) {
-				continue
-			}
-
-			if run.PullRequests != nil && len(run.PullRequests) > 0 {
-				pr := run.PullRequests[0]
-				prURL := fmt.Sprintf("https://github.com/%s/%s/pull/%d", g.owner, *repo.Name, *pr.Number)
-
-				failure := WorkflowFailure{
-					Repo:      *repo.Name,
-					PRNumber:  *pr.Number,
-					Workflow:  *run.Name,
-					StartedAt: run.CreatedAt.Time,
-					URL:       *run.HTMLURL,
-					PRURL:     prURL,
-				}
-
-				failures[prURL] = append(failures[prURL], failure)
-			}
+	}
+	if len(g.include) == 0 {
+		return true
+	}
+	for _, pattern := range g.include {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
 		}
 	}
-
-	return failures, nil
+	return false
 }
@@ -3,6 +3,9 @@ package github_test
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestListAllFailedWorkflows(t *testing.T) {
@@ -100,7 +104,7 @@ func TestGetFailedWorkflows(t *testing.T) {
 		{
 			name: "successful retrieval",
 			setupMock: func(m *mocks.MockClient) {
-				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(nil)
+				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(nil, nil)
 			},
 			prNumber: "123",
 			repo:     "test-repo",
@@ -109,7 +113,7 @@ func TestGetFailedWorkflows(t *testing.T) {
 		{
 			name: "error from client",
 			setupMock: func(m *mocks.MockClient) {
-				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(fmt.Errorf("mock error"))
+				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "test-repo").Return(nil, fmt.Errorf("mock error"))
 			},
 			prNumber: "123",
 			repo:     "test-repo",
@@ -118,7 +122,7 @@ func TestGetFailedWorkflows(t *testing.T) {
 		{
 			name: "empty repository",
 			setupMock: func(m *mocks.MockClient) {
-				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "").Return(fmt.Errorf("repository name is required"))
+				m.EXPECT().GetFailedWorkflows(mock.Anything, "123", "").Return(nil, fmt.Errorf("repository name is required"))
 			},
 			prNumber: "123",
 			repo:     "",
@@ -131,7 +135,7 @@ func TestGetFailedWorkflows(t *testing.T) {
 			mockClient := mocks.NewMockClient(t)
 			tt.setupMock(mockClient)
 
-			err := mockClient.GetFailedWorkflows(context.Background(), tt.prNumber, tt.repo)
+			_, err := mockClient.GetFailedWorkflows(context.Background(), tt.prNumber, tt.repo)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -142,3 +146,174 @@ func TestGetFailedWorkflows(t *testing.T) {
 		})
 	}
 }
+
+func TestGetFailedWorkflowsLatestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/acme/api/pulls/5":
+			fmt.Fprint(w, `{"number": 5, "html_url": "https://github.com/acme/api/pull/5", "head": {"ref": "feature"}}`)
+		case "/api/v3/repos/acme/api/actions/runs":
+			assert.Equal(t, "feature", r.URL.Query().Get("branch"))
+			assert.Equal(t, "completed", r.URL.Query().Get("status"))
+			// Newest first: CI is still failing, Lint has been fixed
+			fmt.Fprint(w, `{"total_count": 4, "workflow_runs": [
+				{"id": 4, "workflow_id": 1, "name": "CI", "conclusion": "failure"},
+				{"id": 3, "workflow_id": 2, "name": "Lint", "conclusion": "success"},
+				{"id": 2, "workflow_id": 2, "name": "Lint", "conclusion": "failure"},
+				{"id": 1, "workflow_id": 1, "name": "CI", "conclusion": "failure"}
+			]}`)
+		case "/api/v3/repos/acme/api/actions/runs/4/jobs":
+			fmt.Fprint(w, `{"total_count": 1, "jobs": [{"name": "test", "conclusion": "failure"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := github.NewClient("token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+	require.NoError(t, err)
+
+	failures, err := client.GetFailedWorkflows(context.Background(), "5", "api")
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, int64(4), failures[0].RunID)
+	assert.Equal(t, "https://github.com/acme/api/pull/5", failures[0].PRURL)
+	assert.Equal(t, []github.JobFailure{{Name: "test"}}, failures[0].Jobs)
+}
+
+func TestFindPullRequest(t *testing.T) {
+	// Open pull requests from the owner's own branch and from two forks
+	open := `[
+		{"number": 1, "head": {"ref": "feature", "label": "acme:feature"}},
+		{"number": 2, "head": {"ref": "fix", "label": "octocat:fix"}},
+		{"number": 3, "head": {"ref": "patch", "label": "octocat:patch"}},
+		{"number": 4, "head": {"ref": "patch", "label": "hubot:patch"}}
+	]`
+
+	tests := []struct {
+		name      string
+		headOwner string
+		branch    string
+		want      int
+		wantErr   bool
+	}{
+		{name: "branch of the owner", branch: "feature", want: 1},
+		{name: "branch of the fork it was pushed to", headOwner: "octocat", branch: "fix", want: 2},
+		{name: "branch of an unknown fork", branch: "fix", want: 2},
+		{name: "branch of several forks", branch: "patch", wantErr: true},
+		{name: "no pull request", branch: "stale", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v3/repos/acme/api/pulls", r.URL.Path)
+				head := r.URL.Query().Get("head")
+				if head == "" {
+					fmt.Fprint(w, open)
+					return
+				}
+				switch head {
+				case "acme:feature":
+					fmt.Fprint(w, `[{"number": 1}]`)
+				case "octocat:fix":
+					fmt.Fprint(w, `[{"number": 2}]`)
+				default:
+					fmt.Fprint(w, `[]`)
+				}
+			}))
+			defer server.Close()
+
+			client, err := github.NewClient("token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+			require.NoError(t, err)
+
+			number, err := client.FindPullRequest(context.Background(), "api", tt.headOwner, tt.branch)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, number)
+		})
+	}
+}
+
+func TestWorkflowFailureInvolves(t *testing.T) {
+	failure := github.WorkflowFailure{PRAuthor: "monalisa", Reviewers: []string{"hubot", "Octocat"}, ReviewTeams: []string{"backend"}}
+
+	assert.True(t, failure.Involves("monalisa"))
+	assert.True(t, failure.Involves("octocat"))
+	assert.False(t, failure.Involves("someone-else"))
+	assert.True(t, failure.Involves("someone-else", "frontend", "Backend"))
+	assert.False(t, failure.Involves("someone-else", "frontend"))
+	assert.False(t, github.WorkflowFailure{}.Involves(""))
+}
+
+func TestPullRequestPeople(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/acme/api/pulls/5":
+			fmt.Fprint(w, `{"number": 5, "user": {"login": "monalisa"},
+				"requested_reviewers": [{"login": "hubot"}], "requested_teams": [{"slug": "backend"}]}`)
+		case "/api/v3/orgs/acme/teams/backend/memberships/hubot":
+			fmt.Fprint(w, `{"state": "active", "role": "member"}`)
+		case "/api/v3/orgs/acme/teams/backend/memberships/octocat":
+			fmt.Fprint(w, `{"state": "pending", "role": "member"}`)
+		case "/api/v3/repos/acme/api/pulls/6":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "Resource not accessible"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := github.NewClient("token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	people, err := client.GetPullRequestPeople(ctx, "api", 5)
+	require.NoError(t, err)
+	assert.Equal(t, github.PullRequestPeople{Author: "monalisa", Reviewers: []string{"hubot"}, Teams: []string{"backend"}}, people)
+
+	_, err = client.GetPullRequestPeople(ctx, "api", 6)
+	assert.Error(t, err)
+
+	for login, want := range map[string]bool{"hubot": true, "octocat": false, "monalisa": false} {
+		member, err := client.IsTeamMember(ctx, "backend", login)
+		require.NoError(t, err)
+		assert.Equal(t, want, member, login)
+	}
+}
+
+func TestFormatFailureComment(t *testing.T) {
+	startedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("with failures", func(t *testing.T) {
+		body := github.FormatFailureComment([]github.WorkflowFailure{
+			{
+				Workflow:  "CI",
+				StartedAt: startedAt,
+				URL:       "https://github.com/owner/repo/actions/runs/1",
+				Jobs: []github.JobFailure{
+					{Name: "build", URL: "https://github.com/owner/repo/actions/runs/1/job/2"},
+				},
+			},
+		})
+
+		assert.True(t, strings.HasPrefix(body, github.CommentMarker))
+		assert.Contains(t, body, "1 failed workflow run(s)")
+		assert.Contains(t, body, "[CI](https://github.com/owner/repo/actions/runs/1)")
+		assert.Contains(t, body, "2024-01-02T03:04:05Z")
+		assert.Contains(t, body, "  - [build](https://github.com/owner/repo/actions/runs/1/job/2)")
+	})
+
+	t.Run("without failures", func(t *testing.T) {
+		body := github.FormatFailureComment(nil)
+
+		assert.True(t, strings.HasPrefix(body, github.CommentMarker))
+		assert.Contains(t, body, "No failed workflow runs")
+	})
+}
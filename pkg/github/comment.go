@@ -0,0 +1,100 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// CommentMarker is the hidden HTML marker used to identify the summary comment
+const CommentMarker = "<!-- gh-workflow-monitor:failure-summary -->"
+
+// CommentFailureSummary creates or updates the failure summary comment on a PR
+func (g *GitHubClient) CommentFailureSummary(ctx context.Context, prNumber string, repo string, failures []WorkflowFailure) error {
+	if repo == "" {
+		return fmt.Errorf("repository name is required")
+	}
+
+	prNum, err := parsePRNumber(prNumber)
+	if err != nil {
+		return err
+	}
+
+	existing, err := g.findSummaryComment(ctx, repo, prNum)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to report and nothing to clear up
+	if existing == nil && len(failures) == 0 {
+		return nil
+	}
+
+	comment := &github.IssueComment{
+		Body: github.String(FormatFailureComment(failures)),
+	}
+
+	if existing != nil {
+		if _, _, err := g.client.Issues.EditComment(ctx, g.owner, repo, existing.GetID(), comment); err != nil {
+			return fmt.Errorf("error updating PR comment: %v", err)
+		}
+		return nil
+	}
+
+	if _, _, err := g.client.Issues.CreateComment(ctx, g.owner, repo, prNum, comment); err != nil {
+		return fmt.Errorf("error creating PR comment: %v", err)
+	}
+
+	return nil
+}
+
+// findSummaryComment returns the existing summary comment on a PR, if any
+func (g *GitHubClient) findSummaryComment(ctx context.Context, repo string, prNum int) (*github.IssueComment, error) {
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		comments, resp, err := g.client.Issues.ListComments(ctx, g.owner, repo, prNum, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error listing PR comments: %v", err)
+		}
+
+		for _, comment := range comments {
+			if strings.Contains(comment.GetBody(), CommentMarker) {
+				return comment, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// FormatFailureComment renders the markdown body of the failure summary comment
+func FormatFailureComment(failures []WorkflowFailure) string {
+	var b strings.Builder
+	b.WriteString(CommentMarker + "\n")
+
+	if len(failures) == 0 {
+		b.WriteString("### :white_check_mark: No failed workflow runs\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "### :x: %d failed workflow run(s)\n\n", len(failures))
+	for _, failure := range failures {
+		fmt.Fprintf(&b, "- **[%s](%s)** started %s\n", failure.Workflow, failure.URL, failure.StartedAt.Format(time.RFC3339))
+		for _, job := range failure.Jobs {
+			fmt.Fprintf(&b, "  - [%s](%s)\n", job.Name, job.URL)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package github_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentFailureSummary(t *testing.T) {
+	failures := []github.WorkflowFailure{
+		{Workflow: "CI", URL: "https://github.com/acme/api/actions/runs/1"},
+	}
+
+	tests := []struct {
+		name     string
+		comments string
+		failures []github.WorkflowFailure
+		// want is the expected write as "METHOD path", or empty for none
+		want string
+	}{
+		{
+			name:     "creates the summary",
+			comments: `[{"id": 1, "body": "LGTM"}]`,
+			failures: failures,
+			want:     "POST /api/v3/repos/acme/api/issues/5/comments",
+		},
+		{
+			name:     "updates the existing summary",
+			comments: fmt.Sprintf(`[{"id": 1, "body": "LGTM"}, {"id": 2, "body": %q}]`, github.CommentMarker+"\nold"),
+			failures: failures,
+			want:     "PATCH /api/v3/repos/acme/api/issues/comments/2",
+		},
+		{
+			name:     "clears the existing summary once green",
+			comments: fmt.Sprintf(`[{"id": 2, "body": %q}]`, github.CommentMarker+"\nold"),
+			want:     "PATCH /api/v3/repos/acme/api/issues/comments/2",
+		},
+		{
+			name:     "does nothing without failures or a summary",
+			comments: `[{"id": 1, "body": "LGTM"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writes []string
+			var body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/acme/api/issues/5/comments" {
+					fmt.Fprint(w, tt.comments)
+					return
+				}
+
+				writes = append(writes, r.Method+" "+r.URL.Path)
+				var comment struct {
+					Body string `json:"body"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&comment))
+				body = comment.Body
+				fmt.Fprint(w, `{"id": 3}`)
+			}))
+			defer server.Close()
+
+			client, err := github.NewClient("token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+			require.NoError(t, err)
+
+			require.NoError(t, client.CommentFailureSummary(context.Background(), "5", "api", tt.failures))
+			if tt.want == "" {
+				assert.Empty(t, writes)
+				return
+			}
+			assert.Equal(t, []string{tt.want}, writes)
+			assert.True(t, strings.HasPrefix(body, github.CommentMarker))
+			assert.Equal(t, github.FormatFailureComment(tt.failures), body)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// maxHistoryPages bounds how far back the run history of a workflow is walked
+const maxHistoryPages = 10
+
+// CommitInfo describes a single commit
+type CommitInfo struct {
+	SHA     string
+	Author  string
+	Message string
+	URL     string
+}
+
+// Culprit describes the commits that may have broken a workflow
+type Culprit struct {
+	Repo     string
+	Branch   string
+	Workflow string
+	// LastGreen is the most recent successful run before the failures, if one was found
+	LastGreen *RunSummary
+	FirstRed  RunSummary
+	// Commits holds the commits between the last green and first red run, oldest first
+	Commits    []CommitInfo
+	CompareURL string
+}
+
+// GetWorkflowStreak retrieves the current streak of a single workflow on a branch.
+// The workflow may be given by name or by file name; an empty branch means the default branch.
+func (g *GitHubClient) GetWorkflowStreak(ctx context.Context, repo, branch, workflow string) (WorkflowStreak, error) {
+	if repo == "" {
+		return WorkflowStreak{}, fmt.Errorf("repository name is required")
+	}
+
+	r, _, err := g.client.Repositories.Get(ctx, g.owner, repo)
+	if err != nil {
+		return WorkflowStreak{}, fmt.Errorf("error getting repository: %v", err)
+	}
+	if branch == "" {
+		branch = r.GetDefaultBranch()
+	}
+
+	wf, err := g.findWorkflow(ctx, repo, workflow)
+	if err != nil {
+		return WorkflowStreak{}, err
+	}
+
+	runs, err := g.walkToLastSuccess(ctx, repo, branch, wf.GetID())
+	if err != nil {
+		return WorkflowStreak{}, err
+	}
+
+	for _, streak := range GroupStreaks(repo, r.GetHTMLURL(), branch, runs) {
+		if streak.WorkflowID == wf.GetID() {
+			return streak, nil
+		}
+	}
+
+	return WorkflowStreak{
+		Repo:       repo,
+		RepoURL:    r.GetHTMLURL(),
+		Branch:     branch,
+		Workflow:   wf.GetName(),
+		WorkflowID: wf.GetID(),
+	}, nil
+}
+
+// FindCulprit identifies the range of commits between the last green and first red run of a failing workflow
+func (g *GitHubClient) FindCulprit(ctx context.Context, streak WorkflowStreak) (*Culprit, error) {
+	if !streak.Failing() {
+		return nil, fmt.Errorf("workflow %s is not failing on %s", streak.Workflow, streak.Branch)
+	}
+
+	// The streak may have been built from a single page of runs, so walk back further if needed
+	if streak.LastSuccess == nil {
+		runs, err := g.walkToLastSuccess(ctx, streak.Repo, streak.Branch, streak.WorkflowID)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range GroupStreaks(streak.Repo, streak.RepoURL, streak.Branch, runs) {
+			if s.WorkflowID == streak.WorkflowID && s.Failing() {
+				streak = s
+			}
+		}
+	}
+
+	culprit := &Culprit{
+		Repo:      streak.Repo,
+		Branch:    streak.Branch,
+		Workflow:  streak.Workflow,
+		LastGreen: streak.LastSuccess,
+		FirstRed:  *streak.FirstFailure(),
+	}
+
+	if culprit.LastGreen == nil {
+		// Never green within the history we can see, so the first red commit is all we have
+		commit, _, err := g.client.Repositories.GetCommit(ctx, g.owner, streak.Repo, culprit.FirstRed.HeadSHA, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error getting commit: %v", err)
+		}
+		culprit.Commits = []CommitInfo{newCommitInfo(commit)}
+		return culprit, nil
+	}
+
+	comparison, _, err := g.client.Repositories.CompareCommits(ctx, g.owner, streak.Repo, culprit.LastGreen.HeadSHA, culprit.FirstRed.HeadSHA, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, fmt.Errorf("error comparing commits: %v", err)
+	}
+
+	culprit.CompareURL = comparison.GetHTMLURL()
+	for _, commit := range comparison.Commits {
+		culprit.Commits = append(culprit.Commits, newCommitInfo(commit))
+	}
+
+	return culprit, nil
+}
+
+// findWorkflow looks up a workflow by name or file name
+func (g *GitHubClient) findWorkflow(ctx context.Context, repo, workflow string) (*github.Workflow, error) {
+	if workflow == "" {
+		return nil, fmt.Errorf("workflow name is required")
+	}
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		workflows, resp, err := g.client.Actions.ListWorkflows(ctx, g.owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error listing workflows: %v", err)
+		}
+
+		for _, wf := range workflows.Workflows {
+			if wf.GetName() == workflow || path.Base(wf.GetPath()) == workflow {
+				return wf, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil, fmt.Errorf("workflow %q not found in %s", workflow, repo)
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// walkToLastSuccess pages back through a workflow's completed runs on a branch until a success is found
+func (g *GitHubClient) walkToLastSuccess(ctx context.Context, repo, branch string, workflowID int64) ([]RunSummary, error) {
+	opts := &github.ListWorkflowRunsOptions{
+		Branch: branch,
+		Status: "completed",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var summaries []RunSummary
+	for page := 0; page < maxHistoryPages; page++ {
+		runs, resp, err := g.client.Actions.ListWorkflowRunsByID(ctx, g.owner, repo, workflowID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting workflow runs: %v", err)
+		}
+
+		found := false
+		for _, run := range runs.WorkflowRuns {
+			summary := newRunSummary(run)
+			summaries = append(summaries, summary)
+			if summary.Succeeded() {
+				found = true
+			}
+		}
+
+		if found || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return summaries, nil
+}
+
+// newCommitInfo converts an API commit to a CommitInfo
+func newCommitInfo(commit *github.RepositoryCommit) CommitInfo {
+	author := commit.GetAuthor().GetLogin()
+	if author == "" {
+		author = commit.GetCommit().GetAuthor().GetName()
+	}
+
+	message, _, _ := strings.Cut(commit.GetCommit().GetMessage(), "\n")
+
+	return CommitInfo{
+		SHA:     commit.GetSHA(),
+		Author:  author,
+		Message: message,
+		URL:     commit.GetHTMLURL(),
+	}
+}
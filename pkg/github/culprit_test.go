@@ -0,0 +1,130 @@
+package github_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCulpritServer serves a CI workflow whose runs on main are, newest first,
+// 4 failure (page 1), 3 failure and 2 success (page 2), and a Deploy workflow that never passed
+func newCulpritServer(t *testing.T, paths *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*paths = append(*paths, r.URL.Path+"?"+r.URL.Query().Get("page"))
+		switch r.URL.Path {
+		case "/api/v3/repos/acme/api":
+			fmt.Fprint(w, `{"name": "api", "default_branch": "main", "html_url": "https://github.com/acme/api"}`)
+		case "/api/v3/repos/acme/api/actions/workflows":
+			fmt.Fprint(w, `{"total_count": 1, "workflows": [{"id": 7, "name": "CI", "path": ".github/workflows/ci.yml"}]}`)
+		case "/api/v3/repos/acme/api/actions/workflows/7/runs":
+			assert.Equal(t, "main", r.URL.Query().Get("branch"))
+			switch r.URL.Query().Get("page") {
+			case "":
+				w.Header().Set("Link", `<https://api/?page=2>; rel="next"`)
+				fmt.Fprint(w, `{"workflow_runs": [
+					{"id": 4, "run_number": 4, "workflow_id": 7, "name": "CI", "conclusion": "failure", "head_sha": "dddd", "created_at": "2024-01-04T00:00:00Z"}
+				]}`)
+			case "2":
+				w.Header().Set("Link", `<https://api/?page=3>; rel="next"`)
+				fmt.Fprint(w, `{"workflow_runs": [
+					{"id": 3, "run_number": 3, "workflow_id": 7, "name": "CI", "conclusion": "failure", "head_sha": "cccc", "created_at": "2024-01-03T00:00:00Z"},
+					{"id": 2, "run_number": 2, "workflow_id": 7, "name": "CI", "conclusion": "success", "head_sha": "bbbb", "created_at": "2024-01-02T00:00:00Z"}
+				]}`)
+			default:
+				t.Errorf("walked past the last success")
+				fmt.Fprint(w, `{"workflow_runs": []}`)
+			}
+		case "/api/v3/repos/acme/api/actions/workflows/8/runs":
+			fmt.Fprint(w, `{"workflow_runs": [
+				{"id": 5, "run_number": 1, "workflow_id": 8, "name": "Deploy", "conclusion": "failure", "head_sha": "dddd", "created_at": "2024-01-04T00:00:00Z"}
+			]}`)
+		case "/api/v3/repos/acme/api/compare/bbbb...cccc":
+			fmt.Fprint(w, `{"html_url": "https://github.com/acme/api/compare/bbbb...cccc", "commits": [
+				{"sha": "cccc", "author": {"login": "octocat"}, "commit": {"message": "Break things\n\nDetails"}}
+			]}`)
+		case "/api/v3/repos/acme/api/commits/dddd":
+			fmt.Fprint(w, `{"sha": "dddd", "commit": {"author": {"name": "Mona"}, "message": "First commit"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+}
+
+func TestGetWorkflowStreak(t *testing.T) {
+	var paths []string
+	server := newCulpritServer(t, &paths)
+	defer server.Close()
+
+	client, err := github.NewClient("token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	streak, err := client.GetWorkflowStreak(ctx, "api", "", "ci.yml")
+	require.NoError(t, err)
+	assert.Equal(t, "main", streak.Branch)
+	assert.Equal(t, int64(7), streak.WorkflowID)
+	require.Len(t, streak.FailingRuns, 2)
+	assert.Equal(t, int64(3), streak.FirstFailure().ID)
+	require.NotNil(t, streak.LastSuccess)
+	assert.Equal(t, int64(2), streak.LastSuccess.ID)
+	assert.Contains(t, paths, "/api/v3/repos/acme/api/actions/workflows/7/runs?2")
+
+	_, err = client.GetWorkflowStreak(ctx, "api", "", "missing.yml")
+	assert.Error(t, err)
+}
+
+func TestFindCulprit(t *testing.T) {
+	var paths []string
+	server := newCulpritServer(t, &paths)
+	defer server.Close()
+
+	client, err := github.NewClient("token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("walks back to the last green run", func(t *testing.T) {
+		// A streak built from a single page has not seen the last success yet
+		streak := github.WorkflowStreak{
+			Repo:        "api",
+			Branch:      "main",
+			Workflow:    "CI",
+			WorkflowID:  7,
+			FailingRuns: []github.RunSummary{{ID: 4, WorkflowID: 7, Conclusion: "failure", HeadSHA: "dddd"}},
+		}
+
+		culprit, err := client.FindCulprit(ctx, streak)
+		require.NoError(t, err)
+		require.NotNil(t, culprit.LastGreen)
+		assert.Equal(t, "bbbb", culprit.LastGreen.HeadSHA)
+		assert.Equal(t, "cccc", culprit.FirstRed.HeadSHA)
+		assert.Equal(t, "https://github.com/acme/api/compare/bbbb...cccc", culprit.CompareURL)
+		assert.Equal(t, []github.CommitInfo{{SHA: "cccc", Author: "octocat", Message: "Break things"}}, culprit.Commits)
+	})
+
+	t.Run("never green", func(t *testing.T) {
+		streak := github.WorkflowStreak{
+			Repo:        "api",
+			Branch:      "main",
+			Workflow:    "Deploy",
+			WorkflowID:  8,
+			FailingRuns: []github.RunSummary{{ID: 4, WorkflowID: 8, Conclusion: "failure", HeadSHA: "dddd"}},
+		}
+
+		culprit, err := client.FindCulprit(ctx, streak)
+		require.NoError(t, err)
+		assert.Nil(t, culprit.LastGreen)
+		assert.Equal(t, []github.CommitInfo{{SHA: "dddd", Author: "Mona", Message: "First commit"}}, culprit.Commits)
+	})
+
+	t.Run("not failing", func(t *testing.T) {
+		_, err := client.FindCulprit(ctx, github.WorkflowStreak{Repo: "api", Workflow: "CI"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,271 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// Diagnosis statuses
+const (
+	DiagnosisPass = "pass"
+	DiagnosisWarn = "warn"
+	DiagnosisFail = "fail"
+	DiagnosisSkip = "skip"
+)
+
+// Diagnosis is the result of one setup check
+type Diagnosis struct {
+	Name   string
+	Status string
+	Detail string
+	// Hint suggests how to fix a failed or warning check
+	Hint string
+}
+
+// Failed reports whether the check failed
+func (d Diagnosis) Failed() bool {
+	return d.Status == DiagnosisFail
+}
+
+// rateLimitWarning is the share of the rate limit below which the budget is reported as low
+const rateLimitWarning = 0.1
+
+// Diagnose checks that the API is reachable, that the credentials are valid and can see
+// the owner's repositories and their workflow runs, and how much of the rate limit is left.
+// Checks that depend on a failed one are skipped.
+func (g *GitHubClient) Diagnose(ctx context.Context) []Diagnosis {
+	results := []Diagnosis{g.diagnoseAPI(ctx)}
+	if results[0].Failed() {
+		return append(results, skipped("Authentication", "Organization", "Repositories", "Actions access", "Rate limit")...)
+	}
+
+	auth := g.diagnoseAuth(ctx)
+	results = append(results, auth...)
+	if auth[0].Failed() {
+		return append(results, skipped("Organization", "Repositories", "Actions access", "Rate limit")...)
+	}
+
+	org := g.diagnoseOwner(ctx)
+	results = append(results, org)
+	if org.Failed() {
+		results = append(results, skipped("Repositories", "Actions access")...)
+	} else {
+		repos, sample := g.diagnoseRepos(ctx)
+		results = append(results, repos)
+		if sample == "" {
+			results = append(results, skipped("Actions access")...)
+		} else {
+			results = append(results, g.diagnoseActions(ctx, sample))
+		}
+	}
+
+	return append(results, g.diagnoseRateLimit(ctx))
+}
+
+func (g *GitHubClient) diagnoseAPI(ctx context.Context) Diagnosis {
+	d := Diagnosis{Name: "API reachable"}
+
+	req, err := g.client.NewRequest(http.MethodGet, "", nil)
+	if err != nil {
+		d.Status, d.Detail = DiagnosisFail, err.Error()
+		return d
+	}
+
+	start := time.Now()
+	resp, err := g.client.Do(ctx, req, nil)
+	if err != nil && resp == nil {
+		d.Status, d.Detail = DiagnosisFail, fmt.Sprintf("%s: %v", g.client.BaseURL, err)
+		d.Hint = "Check your network connection and proxy settings, and --base-url for GitHub Enterprise Server"
+		return d
+	}
+
+	// Any HTTP response, even an error, means the API is reachable
+	d.Status = DiagnosisPass
+	d.Detail = fmt.Sprintf("%s (%s)", g.client.BaseURL, time.Since(start).Round(time.Millisecond))
+	return d
+}
+
+func (g *GitHubClient) diagnoseAuth(ctx context.Context) []Diagnosis {
+	d := Diagnosis{Name: "Authentication"}
+
+	if g.app {
+		repos, _, err := g.client.Apps.ListRepos(ctx, &github.ListOptions{PerPage: 1})
+		if err != nil {
+			d.Status, d.Detail = DiagnosisFail, err.Error()
+			d.Hint = "Check the app ID, installation ID and private key, and that the app is installed on the owner"
+			return []Diagnosis{d}
+		}
+		d.Status = DiagnosisPass
+		d.Detail = fmt.Sprintf("GitHub App installation with access to %d repositories", repos.GetTotalCount())
+		return []Diagnosis{d}
+	}
+
+	user, resp, err := g.client.Users.Get(ctx, "")
+	if err != nil {
+		d.Status, d.Detail = DiagnosisFail, err.Error()
+		d.Hint = "The token is invalid or expired: create a new one, or run `gh auth login` when using the gh login"
+		return []Diagnosis{d}
+	}
+	d.Status, d.Detail = DiagnosisPass, "authenticated as "+user.GetLogin()
+
+	return []Diagnosis{d, diagnoseScopes(resp.Header)}
+}
+
+// diagnoseScopes checks the scopes of a classic personal access token, which GitHub
+// reports in the X-OAuth-Scopes header
+func diagnoseScopes(header http.Header) Diagnosis {
+	d := Diagnosis{Name: "Token scopes"}
+
+	values, ok := header["X-Oauth-Scopes"]
+	if !ok {
+		d.Status = DiagnosisSkip
+		d.Detail = "not reported for fine-grained tokens; access is checked below"
+		return d
+	}
+
+	scopes := map[string]bool{}
+	for _, scope := range strings.Split(strings.Join(values, ","), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes[scope] = true
+		}
+	}
+	d.Detail = strings.Join(values, ",")
+	if d.Detail == "" {
+		d.Detail = "none"
+	}
+
+	if !scopes["repo"] {
+		d.Status = DiagnosisWarn
+		d.Hint = "Add the repo scope to read workflow runs of private repositories"
+		return d
+	}
+
+	d.Status = DiagnosisPass
+	return d
+}
+
+func (g *GitHubClient) diagnoseOwner(ctx context.Context) Diagnosis {
+	d := Diagnosis{Name: "Organization"}
+
+	org, resp, err := g.client.Organizations.Get(ctx, g.owner)
+	if err != nil {
+		d.Status, d.Detail = DiagnosisFail, err.Error()
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			d.Detail = fmt.Sprintf("organization %s not found", g.owner)
+			d.Hint = "Set GITHUB_OWNER or owner to an organization; organization-wide commands do not support user accounts"
+		}
+		return d
+	}
+
+	if g.app {
+		d.Status, d.Detail = DiagnosisPass, org.GetLogin()
+		return d
+	}
+
+	membership, _, err := g.client.Organizations.GetOrgMembership(ctx, "", g.owner)
+	if err != nil || membership.GetState() != "active" {
+		d.Status = DiagnosisWarn
+		d.Detail = fmt.Sprintf("%s is visible, but you are not an active member", org.GetLogin())
+		d.Hint = "Only public repositories may be visible; ask an organization owner for access, or add the read:org scope if you are a member"
+		return d
+	}
+
+	d.Status = DiagnosisPass
+	d.Detail = fmt.Sprintf("%s (%s)", org.GetLogin(), membership.GetRole())
+	return d
+}
+
+// diagnoseRepos checks that repositories are visible and returns one to sample
+func (g *GitHubClient) diagnoseRepos(ctx context.Context) (Diagnosis, string) {
+	d := Diagnosis{Name: "Repositories"}
+
+	repos, resp, err := g.client.Repositories.ListByOrg(ctx, g.owner, &github.RepositoryListByOrgOptions{
+		Type:        "all",
+		Sort:        "updated",
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		d.Status, d.Detail = DiagnosisFail, err.Error()
+		return d, ""
+	}
+
+	var visible []string
+	private := 0
+	for _, repo := range repos {
+		if !g.matchesFilter(repo.GetName()) {
+			continue
+		}
+		visible = append(visible, repo.GetName())
+		if repo.GetPrivate() {
+			private++
+		}
+	}
+
+	if len(visible) == 0 {
+		d.Status = DiagnosisFail
+		d.Detail = fmt.Sprintf("no repositories of %s are visible", g.owner)
+		d.Hint = "Grant the token access to the repositories (fine-grained tokens and apps list them explicitly), and check the repos and exclude_repos settings"
+		return d, ""
+	}
+
+	more := ""
+	if resp.NextPage != 0 {
+		more = "at least "
+	}
+	d.Status = DiagnosisPass
+	d.Detail = fmt.Sprintf("%s%d visible (%d private)", more, len(visible), private)
+	return d, visible[0]
+}
+
+func (g *GitHubClient) diagnoseActions(ctx context.Context, repo string) Diagnosis {
+	d := Diagnosis{Name: "Actions access"}
+
+	_, _, err := g.client.Actions.ListRepositoryWorkflowRuns(ctx, g.owner, repo, &github.ListWorkflowRunsOptions{
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		d.Status = DiagnosisFail
+		d.Detail = fmt.Sprintf("cannot read workflow runs of %s/%s: %v", g.owner, repo, err)
+		d.Hint = "Grant Actions read permission (fine-grained tokens and apps) or the repo scope (classic tokens)"
+		return d
+	}
+
+	d.Status, d.Detail = DiagnosisPass, fmt.Sprintf("can read workflow runs of %s/%s", g.owner, repo)
+	return d
+}
+
+func (g *GitHubClient) diagnoseRateLimit(ctx context.Context) Diagnosis {
+	d := Diagnosis{Name: "Rate limit"}
+
+	limit, err := g.GetRateLimit(ctx)
+	if err != nil {
+		d.Status, d.Detail = DiagnosisFail, err.Error()
+		return d
+	}
+
+	d.Detail = fmt.Sprintf("%d of %d requests left, resets at %s", limit.Remaining, limit.Limit, limit.Reset.Format(time.RFC3339))
+	switch {
+	case limit.Remaining == 0:
+		d.Status = DiagnosisFail
+	case float64(limit.Remaining) < float64(limit.Limit)*rateLimitWarning:
+		d.Status = DiagnosisWarn
+	default:
+		d.Status = DiagnosisPass
+		return d
+	}
+	d.Hint = "Wait for the reset, keep the response cache enabled, or authenticate as a GitHub App for a higher limit"
+	return d
+}
+
+func skipped(names ...string) []Diagnosis {
+	results := make([]Diagnosis, 0, len(names))
+	for _, name := range names {
+		results = append(results, Diagnosis{Name: name, Status: DiagnosisSkip, Detail: "skipped after an earlier failure"})
+	}
+	return results
+}
@@ -0,0 +1,135 @@
+package github_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		owner  string
+		scopes string
+		want   map[string]string
+	}{
+		{
+			name:   "healthy setup",
+			token:  "good",
+			owner:  "acme",
+			scopes: "repo, read:org",
+			want: map[string]string{
+				"API reachable":  github.DiagnosisPass,
+				"Authentication": github.DiagnosisPass,
+				"Token scopes":   github.DiagnosisPass,
+				"Organization":   github.DiagnosisPass,
+				"Repositories":   github.DiagnosisPass,
+				"Actions access": github.DiagnosisPass,
+				"Rate limit":     github.DiagnosisWarn,
+			},
+		},
+		{
+			name:   "missing repo scope",
+			token:  "good",
+			owner:  "acme",
+			scopes: "read:org",
+			want: map[string]string{
+				"Token scopes": github.DiagnosisWarn,
+			},
+		},
+		{
+			name:  "fine-grained token",
+			token: "good",
+			owner: "acme",
+			want: map[string]string{
+				"Token scopes": github.DiagnosisSkip,
+			},
+		},
+		{
+			name:  "invalid token",
+			token: "expired",
+			owner: "acme",
+			want: map[string]string{
+				"API reachable":  github.DiagnosisPass,
+				"Authentication": github.DiagnosisFail,
+				"Organization":   github.DiagnosisSkip,
+				"Rate limit":     github.DiagnosisSkip,
+			},
+		},
+		{
+			name:  "user account as owner",
+			token: "good",
+			owner: "octocat",
+			want: map[string]string{
+				"Organization":   github.DiagnosisFail,
+				"Repositories":   github.DiagnosisSkip,
+				"Actions access": github.DiagnosisSkip,
+				"Rate limit":     github.DiagnosisWarn,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer good" {
+					w.WriteHeader(http.StatusUnauthorized)
+					fmt.Fprint(w, `{"message": "Bad credentials"}`)
+					return
+				}
+				switch r.URL.Path {
+				case "/api/v3/":
+					fmt.Fprint(w, `{}`)
+				case "/api/v3/user":
+					if tt.scopes != "" {
+						w.Header().Set("X-OAuth-Scopes", tt.scopes)
+					}
+					fmt.Fprint(w, `{"login": "monalisa"}`)
+				case "/api/v3/orgs/acme":
+					fmt.Fprint(w, `{"login": "acme"}`)
+				case "/api/v3/user/memberships/orgs/acme":
+					fmt.Fprint(w, `{"state": "active", "role": "member"}`)
+				case "/api/v3/orgs/acme/repos":
+					fmt.Fprint(w, `[{"name": "api", "private": true}, {"name": "web"}]`)
+				case "/api/v3/repos/acme/api/actions/runs":
+					fmt.Fprint(w, `{"total_count": 0, "workflow_runs": []}`)
+				case "/api/v3/rate_limit":
+					fmt.Fprint(w, `{"resources": {"core": {"limit": 5000, "remaining": 12, "reset": 1700000000}}}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprint(w, `{"message": "Not Found"}`)
+				}
+			}))
+			defer server.Close()
+
+			client, err := github.NewClient(tt.token, tt.owner, github.WithBaseURL(server.URL+"/api/v3"))
+			require.NoError(t, err)
+
+			got := map[string]string{}
+			for _, d := range client.Diagnose(context.Background()) {
+				got[d.Name] = d.Status
+				if d.Status == github.DiagnosisFail || d.Status == github.DiagnosisWarn {
+					assert.NotEmpty(t, d.Hint, d.Name)
+				}
+			}
+			for name, status := range tt.want {
+				assert.Equal(t, status, got[name], name)
+			}
+		})
+	}
+
+	client, err := github.NewClient("token", "acme", github.WithBaseURL("http://127.0.0.1:1/api/v3"))
+	require.NoError(t, err)
+	results := client.Diagnose(context.Background())
+	assert.Equal(t, github.DiagnosisFail, results[0].Status)
+	for _, d := range results[1:] {
+		assert.Equal(t, github.DiagnosisSkip, d.Status, d.Name)
+	}
+}
@@ -0,0 +1,52 @@
+package github_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnterpriseServer(t *testing.T) {
+	created := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		assert.Equal(t, "Bearer ghe-token", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v3/orgs/acme/repos":
+			fmt.Fprint(w, `[{"name": "api", "html_url": "https://ghe.example.com/acme/api"}]`)
+		case "/api/v3/repos/acme/api/actions/runs":
+			fmt.Fprintf(w, `{"total_count": 1, "workflow_runs": [{
+				"id": 1, "name": "CI", "status": "completed", "conclusion": "failure", "head_branch": "feature",
+				"created_at": %q, "updated_at": %q,
+				"html_url": "https://ghe.example.com/acme/api/actions/runs/1",
+				"pull_requests": [{"number": 5, "url": "https://ghe.example.com/api/v3/repos/acme/api/pulls/5"}]
+			}]}`, created, created)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := github.NewClient("ghe-token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+	require.NoError(t, err)
+
+	failures, err := client.ListAllFailedWorkflows(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	failure := failures["https://ghe.example.com/acme/api/pull/5"]
+	require.Len(t, failure, 1)
+	assert.Equal(t, "https://ghe.example.com/acme/api/pull/5", failure[0].PRURL)
+	assert.Equal(t, "https://ghe.example.com/acme/api/actions/runs/1", failure[0].URL)
+	assert.Equal(t, []string{"/api/v3/orgs/acme/repos", "/api/v3/repos/acme/api/actions/runs"}, paths)
+
+	_, err = github.NewClient("token", "acme", github.WithBaseURL("ghe.example.com"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,222 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// Tracking issue actions
+const (
+	IssueActionNone    = "none"
+	IssueActionOpen    = "open"
+	IssueActionUpdate  = "update"
+	IssueActionClose   = "close"
+	issueMarkerPattern = "<!-- gh-workflow-monitor:tracking-issue workflow=%d branch=%s -->"
+)
+
+// issueMarkerRegexp matches the marker of any tracking issue
+var issueMarkerRegexp = regexp.MustCompile(`<!-- gh-workflow-monitor:tracking-issue workflow=\d+ branch=\S+ -->`)
+
+// IssueOptions configures how tracking issues are managed
+type IssueOptions struct {
+	// Threshold is the number of consecutive failures that must be exceeded before an issue is opened
+	Threshold int
+	Labels    []string
+	DryRun    bool
+}
+
+// IssueAction describes what was (or, in dry-run mode, would be) done for a workflow
+type IssueAction struct {
+	Repo     string
+	Branch   string
+	Workflow string
+	Action   string
+	IssueURL string
+}
+
+// TrackFailureIssues opens, updates or closes the tracking issue of every workflow streak.
+// The open tracking issues of each repository are listed once, however many of its
+// workflows are tracked. Actions taken before an error are returned with it.
+func (g *GitHubClient) TrackFailureIssues(ctx context.Context, streaks []WorkflowStreak, opts IssueOptions) ([]IssueAction, error) {
+	var actions []IssueAction
+	tracked := make(map[string]map[string]*github.Issue)
+	for _, streak := range streaks {
+		issues, ok := tracked[streak.Repo]
+		if !ok {
+			var err error
+			if issues, err = g.listTrackingIssues(ctx, streak.Repo); err != nil {
+				return actions, err
+			}
+			tracked[streak.Repo] = issues
+		}
+
+		action, err := g.trackFailureIssue(ctx, streak, issues[trackingIssueMarker(streak)], opts)
+		if err != nil {
+			return actions, fmt.Errorf("error tracking issue for %s/%s: %v", streak.Repo, streak.Workflow, err)
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
+
+// trackFailureIssue opens, updates or closes the tracking issue for a workflow streak.
+// existing is its open tracking issue, if any.
+func (g *GitHubClient) trackFailureIssue(ctx context.Context, streak WorkflowStreak, existing *github.Issue, opts IssueOptions) (IssueAction, error) {
+	result := IssueAction{
+		Repo:     streak.Repo,
+		Branch:   streak.Branch,
+		Workflow: streak.Workflow,
+		Action:   IssueActionNone,
+	}
+	if existing != nil {
+		result.IssueURL = existing.GetHTMLURL()
+	}
+
+	switch {
+	case len(streak.FailingRuns) > opts.Threshold && existing == nil:
+		result.Action = IssueActionOpen
+		if opts.DryRun {
+			return result, nil
+		}
+
+		req := &github.IssueRequest{
+			Title: github.String(TrackingIssueTitle(streak)),
+			Body:  github.String(FormatTrackingIssue(streak)),
+		}
+		if len(opts.Labels) > 0 {
+			req.Labels = &opts.Labels
+		}
+
+		issue, _, err := g.client.Issues.Create(ctx, g.owner, streak.Repo, req)
+		if err != nil {
+			return result, fmt.Errorf("error creating issue: %v", err)
+		}
+		result.IssueURL = issue.GetHTMLURL()
+
+	case streak.Failing() && existing != nil:
+		// Editing an unchanged issue would only notify its watchers
+		body := FormatTrackingIssue(streak)
+		if body == existing.GetBody() {
+			return result, nil
+		}
+
+		result.Action = IssueActionUpdate
+		if opts.DryRun {
+			return result, nil
+		}
+
+		_, _, err := g.client.Issues.Edit(ctx, g.owner, streak.Repo, existing.GetNumber(), &github.IssueRequest{
+			Body: github.String(body),
+		})
+		if err != nil {
+			return result, fmt.Errorf("error updating issue: %v", err)
+		}
+
+	case !streak.Failing() && streak.LastSuccess != nil && existing != nil:
+		result.Action = IssueActionClose
+		if opts.DryRun {
+			return result, nil
+		}
+
+		comment := &github.IssueComment{
+			Body: github.String(fmt.Sprintf("The workflow is passing again as of [run #%d](%s).", streak.LastSuccess.Number, streak.LastSuccess.URL)),
+		}
+		if _, _, err := g.client.Issues.CreateComment(ctx, g.owner, streak.Repo, existing.GetNumber(), comment); err != nil {
+			return result, fmt.Errorf("error commenting on issue: %v", err)
+		}
+
+		_, _, err := g.client.Issues.Edit(ctx, g.owner, streak.Repo, existing.GetNumber(), &github.IssueRequest{
+			State:       github.String("closed"),
+			StateReason: github.String("completed"),
+		})
+		if err != nil {
+			return result, fmt.Errorf("error closing issue: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// listTrackingIssues returns the open tracking issues of a repository by their marker.
+// Issues are matched by their hidden marker alone, so changing the labels does not open duplicates.
+func (g *GitHubClient) listTrackingIssues(ctx context.Context, repo string) (map[string]*github.Issue, error) {
+	tracked := make(map[string]*github.Issue)
+	opts := &github.IssueListByRepoOptions{
+		State: "open",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		issues, resp, err := g.client.Issues.ListByRepo(ctx, g.owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error listing issues: %v", err)
+		}
+
+		for _, issue := range issues {
+			if issue.IsPullRequest() {
+				continue
+			}
+			if marker := issueMarkerRegexp.FindString(issue.GetBody()); marker != "" {
+				tracked[marker] = issue
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return tracked, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// trackingIssueMarker returns the hidden marker identifying the tracking issue of a workflow
+func trackingIssueMarker(streak WorkflowStreak) string {
+	return fmt.Sprintf(issueMarkerPattern, streak.WorkflowID, streak.Branch)
+}
+
+// TrackingIssueTitle returns the title of the tracking issue for a workflow streak
+func TrackingIssueTitle(streak WorkflowStreak) string {
+	return fmt.Sprintf("Workflow %q is failing on %s", streak.Workflow, streak.Branch)
+}
+
+// FormatTrackingIssue renders the markdown body of the tracking issue for a workflow streak
+func FormatTrackingIssue(streak WorkflowStreak) string {
+	var b strings.Builder
+	b.WriteString(trackingIssueMarker(streak) + "\n")
+
+	fmt.Fprintf(&b, "The **%s** workflow has failed on `%s` for the last %d consecutive run(s).\n\n", streak.Workflow, streak.Branch, len(streak.FailingRuns))
+
+	if first := streak.FirstFailure(); first != nil {
+		fmt.Fprintf(&b, "First bad commit: %s\n", commitLink(streak.RepoURL, first.HeadSHA))
+	}
+	if streak.LastSuccess != nil {
+		fmt.Fprintf(&b, "Last successful run: [#%d](%s) on %s\n", streak.LastSuccess.Number, streak.LastSuccess.URL, commitLink(streak.RepoURL, streak.LastSuccess.HeadSHA))
+	} else {
+		b.WriteString("Last successful run: none found\n")
+	}
+
+	b.WriteString("\nFailing runs:\n")
+	for _, run := range streak.FailingRuns {
+		fmt.Fprintf(&b, "- [#%d](%s) %s on %s\n", run.Number, run.URL, run.Conclusion, commitLink(streak.RepoURL, run.HeadSHA))
+	}
+
+	return b.String()
+}
+
+// commitLink renders a markdown link to a commit
+func commitLink(repoURL, sha string) string {
+	short := sha
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if repoURL == "" {
+		return "`" + short + "`"
+	}
+	return fmt.Sprintf("[`%s`](%s/commit/%s)", short, repoURL, sha)
+}
@@ -0,0 +1,173 @@
+package github_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatTrackingIssue(t *testing.T) {
+	streak := github.WorkflowStreak{
+		Repo:       "repo",
+		RepoURL:    "https://github.com/owner/repo",
+		Branch:     "main",
+		Workflow:   "CI",
+		WorkflowID: 42,
+		FailingRuns: []github.RunSummary{
+			{Number: 12, Conclusion: "failure", HeadSHA: "bbbbbbbbbbbb", URL: "https://github.com/owner/repo/actions/runs/12"},
+			{Number: 11, Conclusion: "timed_out", HeadSHA: "aaaaaaaaaaaa", URL: "https://github.com/owner/repo/actions/runs/11"},
+		},
+		LastSuccess: &github.RunSummary{Number: 10, Conclusion: "success", HeadSHA: "cccccccccccc", URL: "https://github.com/owner/repo/actions/runs/10"},
+	}
+
+	body := github.FormatTrackingIssue(streak)
+
+	assert.True(t, strings.HasPrefix(body, "<!-- gh-workflow-monitor:tracking-issue workflow=42 branch=main -->"))
+	assert.Contains(t, body, "last 2 consecutive run(s)")
+	assert.Contains(t, body, "First bad commit: [`aaaaaaa`](https://github.com/owner/repo/commit/aaaaaaaaaaaa)")
+	assert.Contains(t, body, "Last successful run: [#10](https://github.com/owner/repo/actions/runs/10)")
+	assert.Contains(t, body, "- [#12](https://github.com/owner/repo/actions/runs/12) failure")
+	assert.Equal(t, `Workflow "CI" is failing on main`, github.TrackingIssueTitle(streak))
+}
+
+func TestTrackFailureIssues(t *testing.T) {
+	failing := github.WorkflowStreak{
+		Repo:        "api",
+		Branch:      "main",
+		Workflow:    "CI",
+		WorkflowID:  42,
+		FailingRuns: []github.RunSummary{{Number: 3}, {Number: 2}},
+	}
+	green := github.WorkflowStreak{
+		Repo:        "api",
+		Branch:      "main",
+		Workflow:    "CI",
+		WorkflowID:  42,
+		LastSuccess: &github.RunSummary{Number: 4, URL: "https://github.com/acme/api/actions/runs/4"},
+	}
+	tracked := fmt.Sprintf(`[{"number": 7, "html_url": "https://github.com/acme/api/issues/7", "body": %q}]`,
+		"<!-- gh-workflow-monitor:tracking-issue workflow=42 branch=main -->\nold")
+	unrelated := `[{"number": 8, "body": "something else"}]`
+	current := fmt.Sprintf(`[{"number": 7, "html_url": "https://github.com/acme/api/issues/7", "body": %q}]`,
+		github.FormatTrackingIssue(failing))
+
+	tests := []struct {
+		name       string
+		streak     github.WorkflowStreak
+		issues     string
+		dryRun     bool
+		wantAction string
+		wantWrites []string
+	}{
+		{
+			name:       "opens an issue past the threshold",
+			streak:     failing,
+			issues:     unrelated,
+			wantAction: github.IssueActionOpen,
+			wantWrites: []string{"POST /api/v3/repos/acme/api/issues"},
+		},
+		{
+			name:       "updates the tracked issue",
+			streak:     failing,
+			issues:     tracked,
+			wantAction: github.IssueActionUpdate,
+			wantWrites: []string{"PATCH /api/v3/repos/acme/api/issues/7"},
+		},
+		{
+			name:       "leaves an up to date issue alone",
+			streak:     failing,
+			issues:     current,
+			wantAction: github.IssueActionNone,
+		},
+		{
+			name:       "closes the tracked issue once green",
+			streak:     green,
+			issues:     tracked,
+			wantAction: github.IssueActionClose,
+			wantWrites: []string{"POST /api/v3/repos/acme/api/issues/7/comments", "PATCH /api/v3/repos/acme/api/issues/7"},
+		},
+		{
+			name:       "dry run changes nothing",
+			streak:     green,
+			issues:     tracked,
+			dryRun:     true,
+			wantAction: github.IssueActionClose,
+		},
+		{
+			name:       "green without an issue does nothing",
+			streak:     green,
+			issues:     unrelated,
+			wantAction: github.IssueActionNone,
+		},
+		{
+			name:       "failing below the threshold does nothing",
+			streak:     github.WorkflowStreak{Repo: "api", Branch: "main", WorkflowID: 42, FailingRuns: []github.RunSummary{{Number: 3}}},
+			issues:     unrelated,
+			wantAction: github.IssueActionNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writes []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/acme/api/issues" {
+					// Tracking issues are found by their marker, whatever their labels
+					assert.Empty(t, r.URL.Query().Get("labels"))
+					fmt.Fprint(w, tt.issues)
+					return
+				}
+
+				writes = append(writes, r.Method+" "+r.URL.Path)
+				fmt.Fprint(w, `{"number": 9, "html_url": "https://github.com/acme/api/issues/9"}`)
+			}))
+			defer server.Close()
+
+			client, err := github.NewClient("token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+			require.NoError(t, err)
+
+			actions, err := client.TrackFailureIssues(context.Background(), []github.WorkflowStreak{tt.streak}, github.IssueOptions{
+				Threshold: 1,
+				Labels:    []string{"ci-failure"},
+				DryRun:    tt.dryRun,
+			})
+			require.NoError(t, err)
+			require.Len(t, actions, 1)
+			assert.Equal(t, tt.wantAction, actions[0].Action)
+			assert.Equal(t, tt.wantWrites, writes)
+		})
+	}
+}
+
+func TestTrackFailureIssuesListsOncePerRepo(t *testing.T) {
+	streak := func(repo string, workflowID int64) github.WorkflowStreak {
+		return github.WorkflowStreak{Repo: repo, Branch: "main", Workflow: "CI", WorkflowID: workflowID, FailingRuns: []github.RunSummary{{Number: 1}}}
+	}
+
+	listed := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listed[r.URL.Path]++
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	client, err := github.NewClient("token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+	require.NoError(t, err)
+
+	actions, err := client.TrackFailureIssues(context.Background(), []github.WorkflowStreak{
+		streak("api", 1), streak("api", 2), streak("api", 3), streak("web", 4),
+	}, github.IssueOptions{Threshold: 5})
+	require.NoError(t, err)
+	assert.Len(t, actions, 4)
+	assert.Equal(t, map[string]int{
+		"/api/v3/repos/acme/api/issues": 1,
+		"/api/v3/repos/acme/web/issues": 1,
+	}, listed)
+}
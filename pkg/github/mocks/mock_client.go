@@ -22,22 +22,305 @@ func (_m *MockClient) EXPECT() *MockClient_Expecter {
 	return &MockClient_Expecter{mock: &_m.Mock}
 }
 
+// CommentFailureSummary provides a mock function with given fields: ctx, prNumber, repo, failures
+func (_m *MockClient) CommentFailureSummary(ctx context.Context, prNumber string, repo string, failures []github.WorkflowFailure) error {
+	ret := _m.Called(ctx, prNumber, repo, failures)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CommentFailureSummary")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []github.WorkflowFailure) error); ok {
+		r0 = rf(ctx, prNumber, repo, failures)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockClient_CommentFailureSummary_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CommentFailureSummary'
+type MockClient_CommentFailureSummary_Call struct {
+	*mock.Call
+}
+
+// CommentFailureSummary is a helper method to define mock.On call
+//   - ctx context.Context
+//   - prNumber string
+//   - repo string
+//   - failures []github.WorkflowFailure
+func (_e *MockClient_Expecter) CommentFailureSummary(ctx interface{}, prNumber interface{}, repo interface{}, failures interface{}) *MockClient_CommentFailureSummary_Call {
+	return &MockClient_CommentFailureSummary_Call{Call: _e.mock.On("CommentFailureSummary", ctx, prNumber, repo, failures)}
+}
+
+func (_c *MockClient_CommentFailureSummary_Call) Run(run func(ctx context.Context, prNumber string, repo string, failures []github.WorkflowFailure)) *MockClient_CommentFailureSummary_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].([]github.WorkflowFailure))
+	})
+	return _c
+}
+
+func (_c *MockClient_CommentFailureSummary_Call) Return(_a0 error) *MockClient_CommentFailureSummary_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockClient_CommentFailureSummary_Call) RunAndReturn(run func(context.Context, string, string, []github.WorkflowFailure) error) *MockClient_CommentFailureSummary_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CurrentUser provides a mock function with given fields: ctx
+func (_m *MockClient) CurrentUser(ctx context.Context) (string, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CurrentUser")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (string, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) string); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_CurrentUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CurrentUser'
+type MockClient_CurrentUser_Call struct {
+	*mock.Call
+}
+
+// CurrentUser is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockClient_Expecter) CurrentUser(ctx interface{}) *MockClient_CurrentUser_Call {
+	return &MockClient_CurrentUser_Call{Call: _e.mock.On("CurrentUser", ctx)}
+}
+
+func (_c *MockClient_CurrentUser_Call) Run(run func(ctx context.Context)) *MockClient_CurrentUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockClient_CurrentUser_Call) Return(_a0 string, _a1 error) *MockClient_CurrentUser_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_CurrentUser_Call) RunAndReturn(run func(context.Context) (string, error)) *MockClient_CurrentUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Diagnose provides a mock function with given fields: ctx
+func (_m *MockClient) Diagnose(ctx context.Context) []github.Diagnosis {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Diagnose")
+	}
+
+	var r0 []github.Diagnosis
+	if rf, ok := ret.Get(0).(func(context.Context) []github.Diagnosis); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]github.Diagnosis)
+		}
+	}
+
+	return r0
+}
+
+// MockClient_Diagnose_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Diagnose'
+type MockClient_Diagnose_Call struct {
+	*mock.Call
+}
+
+// Diagnose is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockClient_Expecter) Diagnose(ctx interface{}) *MockClient_Diagnose_Call {
+	return &MockClient_Diagnose_Call{Call: _e.mock.On("Diagnose", ctx)}
+}
+
+func (_c *MockClient_Diagnose_Call) Run(run func(ctx context.Context)) *MockClient_Diagnose_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockClient_Diagnose_Call) Return(_a0 []github.Diagnosis) *MockClient_Diagnose_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockClient_Diagnose_Call) RunAndReturn(run func(context.Context) []github.Diagnosis) *MockClient_Diagnose_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FindCulprit provides a mock function with given fields: ctx, streak
+func (_m *MockClient) FindCulprit(ctx context.Context, streak github.WorkflowStreak) (*github.Culprit, error) {
+	ret := _m.Called(ctx, streak)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindCulprit")
+	}
+
+	var r0 *github.Culprit
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, github.WorkflowStreak) (*github.Culprit, error)); ok {
+		return rf(ctx, streak)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, github.WorkflowStreak) *github.Culprit); ok {
+		r0 = rf(ctx, streak)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.Culprit)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, github.WorkflowStreak) error); ok {
+		r1 = rf(ctx, streak)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_FindCulprit_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindCulprit'
+type MockClient_FindCulprit_Call struct {
+	*mock.Call
+}
+
+// FindCulprit is a helper method to define mock.On call
+//   - ctx context.Context
+//   - streak github.WorkflowStreak
+func (_e *MockClient_Expecter) FindCulprit(ctx interface{}, streak interface{}) *MockClient_FindCulprit_Call {
+	return &MockClient_FindCulprit_Call{Call: _e.mock.On("FindCulprit", ctx, streak)}
+}
+
+func (_c *MockClient_FindCulprit_Call) Run(run func(ctx context.Context, streak github.WorkflowStreak)) *MockClient_FindCulprit_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(github.WorkflowStreak))
+	})
+	return _c
+}
+
+func (_c *MockClient_FindCulprit_Call) Return(_a0 *github.Culprit, _a1 error) *MockClient_FindCulprit_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_FindCulprit_Call) RunAndReturn(run func(context.Context, github.WorkflowStreak) (*github.Culprit, error)) *MockClient_FindCulprit_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FindPullRequest provides a mock function with given fields: ctx, repo, headOwner, branch
+func (_m *MockClient) FindPullRequest(ctx context.Context, repo string, headOwner string, branch string) (int, error) {
+	ret := _m.Called(ctx, repo, headOwner, branch)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindPullRequest")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (int, error)); ok {
+		return rf(ctx, repo, headOwner, branch)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) int); ok {
+		r0 = rf(ctx, repo, headOwner, branch)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, repo, headOwner, branch)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_FindPullRequest_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindPullRequest'
+type MockClient_FindPullRequest_Call struct {
+	*mock.Call
+}
+
+// FindPullRequest is a helper method to define mock.On call
+//   - ctx context.Context
+//   - repo string
+//   - headOwner string
+//   - branch string
+func (_e *MockClient_Expecter) FindPullRequest(ctx interface{}, repo interface{}, headOwner interface{}, branch interface{}) *MockClient_FindPullRequest_Call {
+	return &MockClient_FindPullRequest_Call{Call: _e.mock.On("FindPullRequest", ctx, repo, headOwner, branch)}
+}
+
+func (_c *MockClient_FindPullRequest_Call) Run(run func(ctx context.Context, repo string, headOwner string, branch string)) *MockClient_FindPullRequest_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *MockClient_FindPullRequest_Call) Return(_a0 int, _a1 error) *MockClient_FindPullRequest_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_FindPullRequest_Call) RunAndReturn(run func(context.Context, string, string, string) (int, error)) *MockClient_FindPullRequest_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetFailedWorkflows provides a mock function with given fields: ctx, prNumber, repo
-func (_m *MockClient) GetFailedWorkflows(ctx context.Context, prNumber string, repo string) error {
+func (_m *MockClient) GetFailedWorkflows(ctx context.Context, prNumber string, repo string) ([]github.WorkflowFailure, error) {
 	ret := _m.Called(ctx, prNumber, repo)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetFailedWorkflows")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+	var r0 []github.WorkflowFailure
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]github.WorkflowFailure, error)); ok {
+		return rf(ctx, prNumber, repo)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []github.WorkflowFailure); ok {
 		r0 = rf(ctx, prNumber, repo)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]github.WorkflowFailure)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, prNumber, repo)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // MockClient_GetFailedWorkflows_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFailedWorkflows'
@@ -60,12 +343,243 @@ func (_c *MockClient_GetFailedWorkflows_Call) Run(run func(ctx context.Context,
 	return _c
 }
 
-func (_c *MockClient_GetFailedWorkflows_Call) Return(_a0 error) *MockClient_GetFailedWorkflows_Call {
-	_c.Call.Return(_a0)
+func (_c *MockClient_GetFailedWorkflows_Call) Return(_a0 []github.WorkflowFailure, _a1 error) *MockClient_GetFailedWorkflows_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_GetFailedWorkflows_Call) RunAndReturn(run func(context.Context, string, string) ([]github.WorkflowFailure, error)) *MockClient_GetFailedWorkflows_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetPullRequestPeople provides a mock function with given fields: ctx, repo, number
+func (_m *MockClient) GetPullRequestPeople(ctx context.Context, repo string, number int) (github.PullRequestPeople, error) {
+	ret := _m.Called(ctx, repo, number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPullRequestPeople")
+	}
+
+	var r0 github.PullRequestPeople
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (github.PullRequestPeople, error)); ok {
+		return rf(ctx, repo, number)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) github.PullRequestPeople); ok {
+		r0 = rf(ctx, repo, number)
+	} else {
+		r0 = ret.Get(0).(github.PullRequestPeople)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, repo, number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_GetPullRequestPeople_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetPullRequestPeople'
+type MockClient_GetPullRequestPeople_Call struct {
+	*mock.Call
+}
+
+// GetPullRequestPeople is a helper method to define mock.On call
+//   - ctx context.Context
+//   - repo string
+//   - number int
+func (_e *MockClient_Expecter) GetPullRequestPeople(ctx interface{}, repo interface{}, number interface{}) *MockClient_GetPullRequestPeople_Call {
+	return &MockClient_GetPullRequestPeople_Call{Call: _e.mock.On("GetPullRequestPeople", ctx, repo, number)}
+}
+
+func (_c *MockClient_GetPullRequestPeople_Call) Run(run func(ctx context.Context, repo string, number int)) *MockClient_GetPullRequestPeople_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(int))
+	})
 	return _c
 }
 
-func (_c *MockClient_GetFailedWorkflows_Call) RunAndReturn(run func(context.Context, string, string) error) *MockClient_GetFailedWorkflows_Call {
+func (_c *MockClient_GetPullRequestPeople_Call) Return(_a0 github.PullRequestPeople, _a1 error) *MockClient_GetPullRequestPeople_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_GetPullRequestPeople_Call) RunAndReturn(run func(context.Context, string, int) (github.PullRequestPeople, error)) *MockClient_GetPullRequestPeople_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetRateLimit provides a mock function with given fields: ctx
+func (_m *MockClient) GetRateLimit(ctx context.Context) (github.RateLimit, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRateLimit")
+	}
+
+	var r0 github.RateLimit
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (github.RateLimit, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) github.RateLimit); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(github.RateLimit)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_GetRateLimit_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRateLimit'
+type MockClient_GetRateLimit_Call struct {
+	*mock.Call
+}
+
+// GetRateLimit is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockClient_Expecter) GetRateLimit(ctx interface{}) *MockClient_GetRateLimit_Call {
+	return &MockClient_GetRateLimit_Call{Call: _e.mock.On("GetRateLimit", ctx)}
+}
+
+func (_c *MockClient_GetRateLimit_Call) Run(run func(ctx context.Context)) *MockClient_GetRateLimit_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockClient_GetRateLimit_Call) Return(_a0 github.RateLimit, _a1 error) *MockClient_GetRateLimit_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_GetRateLimit_Call) RunAndReturn(run func(context.Context) (github.RateLimit, error)) *MockClient_GetRateLimit_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetWorkflowStreak provides a mock function with given fields: ctx, repo, branch, workflow
+func (_m *MockClient) GetWorkflowStreak(ctx context.Context, repo string, branch string, workflow string) (github.WorkflowStreak, error) {
+	ret := _m.Called(ctx, repo, branch, workflow)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWorkflowStreak")
+	}
+
+	var r0 github.WorkflowStreak
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (github.WorkflowStreak, error)); ok {
+		return rf(ctx, repo, branch, workflow)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) github.WorkflowStreak); ok {
+		r0 = rf(ctx, repo, branch, workflow)
+	} else {
+		r0 = ret.Get(0).(github.WorkflowStreak)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, repo, branch, workflow)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_GetWorkflowStreak_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetWorkflowStreak'
+type MockClient_GetWorkflowStreak_Call struct {
+	*mock.Call
+}
+
+// GetWorkflowStreak is a helper method to define mock.On call
+//   - ctx context.Context
+//   - repo string
+//   - branch string
+//   - workflow string
+func (_e *MockClient_Expecter) GetWorkflowStreak(ctx interface{}, repo interface{}, branch interface{}, workflow interface{}) *MockClient_GetWorkflowStreak_Call {
+	return &MockClient_GetWorkflowStreak_Call{Call: _e.mock.On("GetWorkflowStreak", ctx, repo, branch, workflow)}
+}
+
+func (_c *MockClient_GetWorkflowStreak_Call) Run(run func(ctx context.Context, repo string, branch string, workflow string)) *MockClient_GetWorkflowStreak_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *MockClient_GetWorkflowStreak_Call) Return(_a0 github.WorkflowStreak, _a1 error) *MockClient_GetWorkflowStreak_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_GetWorkflowStreak_Call) RunAndReturn(run func(context.Context, string, string, string) (github.WorkflowStreak, error)) *MockClient_GetWorkflowStreak_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IsTeamMember provides a mock function with given fields: ctx, team, login
+func (_m *MockClient) IsTeamMember(ctx context.Context, team string, login string) (bool, error) {
+	ret := _m.Called(ctx, team, login)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsTeamMember")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (bool, error)); ok {
+		return rf(ctx, team, login)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) bool); ok {
+		r0 = rf(ctx, team, login)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, team, login)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_IsTeamMember_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IsTeamMember'
+type MockClient_IsTeamMember_Call struct {
+	*mock.Call
+}
+
+// IsTeamMember is a helper method to define mock.On call
+//   - ctx context.Context
+//   - team string
+//   - login string
+func (_e *MockClient_Expecter) IsTeamMember(ctx interface{}, team interface{}, login interface{}) *MockClient_IsTeamMember_Call {
+	return &MockClient_IsTeamMember_Call{Call: _e.mock.On("IsTeamMember", ctx, team, login)}
+}
+
+func (_c *MockClient_IsTeamMember_Call) Run(run func(ctx context.Context, team string, login string)) *MockClient_IsTeamMember_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockClient_IsTeamMember_Call) Return(_a0 bool, _a1 error) *MockClient_IsTeamMember_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_IsTeamMember_Call) RunAndReturn(run func(context.Context, string, string) (bool, error)) *MockClient_IsTeamMember_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -129,6 +643,253 @@ func (_c *MockClient_ListAllFailedWorkflows_Call) RunAndReturn(run func(context.
 	return _c
 }
 
+// ListBranchStreaks provides a mock function with given fields: ctx, opts
+func (_m *MockClient) ListBranchStreaks(ctx context.Context, opts github.BranchOptions) ([]github.WorkflowStreak, error) {
+	ret := _m.Called(ctx, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListBranchStreaks")
+	}
+
+	var r0 []github.WorkflowStreak
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, github.BranchOptions) ([]github.WorkflowStreak, error)); ok {
+		return rf(ctx, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, github.BranchOptions) []github.WorkflowStreak); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]github.WorkflowStreak)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, github.BranchOptions) error); ok {
+		r1 = rf(ctx, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_ListBranchStreaks_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListBranchStreaks'
+type MockClient_ListBranchStreaks_Call struct {
+	*mock.Call
+}
+
+// ListBranchStreaks is a helper method to define mock.On call
+//   - ctx context.Context
+//   - opts github.BranchOptions
+func (_e *MockClient_Expecter) ListBranchStreaks(ctx interface{}, opts interface{}) *MockClient_ListBranchStreaks_Call {
+	return &MockClient_ListBranchStreaks_Call{Call: _e.mock.On("ListBranchStreaks", ctx, opts)}
+}
+
+func (_c *MockClient_ListBranchStreaks_Call) Run(run func(ctx context.Context, opts github.BranchOptions)) *MockClient_ListBranchStreaks_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(github.BranchOptions))
+	})
+	return _c
+}
+
+func (_c *MockClient_ListBranchStreaks_Call) Return(_a0 []github.WorkflowStreak, _a1 error) *MockClient_ListBranchStreaks_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_ListBranchStreaks_Call) RunAndReturn(run func(context.Context, github.BranchOptions) ([]github.WorkflowStreak, error)) *MockClient_ListBranchStreaks_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListCompletedRuns provides a mock function with given fields: ctx, days
+func (_m *MockClient) ListCompletedRuns(ctx context.Context, days int) ([]github.RunSummary, error) {
+	ret := _m.Called(ctx, days)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListCompletedRuns")
+	}
+
+	var r0 []github.RunSummary
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]github.RunSummary, error)); ok {
+		return rf(ctx, days)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []github.RunSummary); ok {
+		r0 = rf(ctx, days)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]github.RunSummary)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, days)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_ListCompletedRuns_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListCompletedRuns'
+type MockClient_ListCompletedRuns_Call struct {
+	*mock.Call
+}
+
+// ListCompletedRuns is a helper method to define mock.On call
+//   - ctx context.Context
+//   - days int
+func (_e *MockClient_Expecter) ListCompletedRuns(ctx interface{}, days interface{}) *MockClient_ListCompletedRuns_Call {
+	return &MockClient_ListCompletedRuns_Call{Call: _e.mock.On("ListCompletedRuns", ctx, days)}
+}
+
+func (_c *MockClient_ListCompletedRuns_Call) Run(run func(ctx context.Context, days int)) *MockClient_ListCompletedRuns_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *MockClient_ListCompletedRuns_Call) Return(_a0 []github.RunSummary, _a1 error) *MockClient_ListCompletedRuns_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_ListCompletedRuns_Call) RunAndReturn(run func(context.Context, int) ([]github.RunSummary, error)) *MockClient_ListCompletedRuns_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SyncRuns provides a mock function with given fields: ctx, days, watermarks
+func (_m *MockClient) SyncRuns(ctx context.Context, days int, watermarks map[string]github.Watermark) ([]github.RunSummary, map[string]github.Watermark, error) {
+	ret := _m.Called(ctx, days, watermarks)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SyncRuns")
+	}
+
+	var r0 []github.RunSummary
+	var r1 map[string]github.Watermark
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, map[string]github.Watermark) ([]github.RunSummary, map[string]github.Watermark, error)); ok {
+		return rf(ctx, days, watermarks)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, map[string]github.Watermark) []github.RunSummary); ok {
+		r0 = rf(ctx, days, watermarks)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]github.RunSummary)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, map[string]github.Watermark) map[string]github.Watermark); ok {
+		r1 = rf(ctx, days, watermarks)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(map[string]github.Watermark)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int, map[string]github.Watermark) error); ok {
+		r2 = rf(ctx, days, watermarks)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MockClient_SyncRuns_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SyncRuns'
+type MockClient_SyncRuns_Call struct {
+	*mock.Call
+}
+
+// SyncRuns is a helper method to define mock.On call
+//   - ctx context.Context
+//   - days int
+//   - watermarks map[string]github.Watermark
+func (_e *MockClient_Expecter) SyncRuns(ctx interface{}, days interface{}, watermarks interface{}) *MockClient_SyncRuns_Call {
+	return &MockClient_SyncRuns_Call{Call: _e.mock.On("SyncRuns", ctx, days, watermarks)}
+}
+
+func (_c *MockClient_SyncRuns_Call) Run(run func(ctx context.Context, days int, watermarks map[string]github.Watermark)) *MockClient_SyncRuns_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(map[string]github.Watermark))
+	})
+	return _c
+}
+
+func (_c *MockClient_SyncRuns_Call) Return(_a0 []github.RunSummary, _a1 map[string]github.Watermark, _a2 error) *MockClient_SyncRuns_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *MockClient_SyncRuns_Call) RunAndReturn(run func(context.Context, int, map[string]github.Watermark) ([]github.RunSummary, map[string]github.Watermark, error)) *MockClient_SyncRuns_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TrackFailureIssues provides a mock function with given fields: ctx, streaks, opts
+func (_m *MockClient) TrackFailureIssues(ctx context.Context, streaks []github.WorkflowStreak, opts github.IssueOptions) ([]github.IssueAction, error) {
+	ret := _m.Called(ctx, streaks, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TrackFailureIssues")
+	}
+
+	var r0 []github.IssueAction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []github.WorkflowStreak, github.IssueOptions) ([]github.IssueAction, error)); ok {
+		return rf(ctx, streaks, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []github.WorkflowStreak, github.IssueOptions) []github.IssueAction); ok {
+		r0 = rf(ctx, streaks, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]github.IssueAction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []github.WorkflowStreak, github.IssueOptions) error); ok {
+		r1 = rf(ctx, streaks, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_TrackFailureIssues_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TrackFailureIssues'
+type MockClient_TrackFailureIssues_Call struct {
+	*mock.Call
+}
+
+// TrackFailureIssues is a helper method to define mock.On call
+//   - ctx context.Context
+//   - streaks []github.WorkflowStreak
+//   - opts github.IssueOptions
+func (_e *MockClient_Expecter) TrackFailureIssues(ctx interface{}, streaks interface{}, opts interface{}) *MockClient_TrackFailureIssues_Call {
+	return &MockClient_TrackFailureIssues_Call{Call: _e.mock.On("TrackFailureIssues", ctx, streaks, opts)}
+}
+
+func (_c *MockClient_TrackFailureIssues_Call) Run(run func(ctx context.Context, streaks []github.WorkflowStreak, opts github.IssueOptions)) *MockClient_TrackFailureIssues_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]github.WorkflowStreak), args[2].(github.IssueOptions))
+	})
+	return _c
+}
+
+func (_c *MockClient_TrackFailureIssues_Call) Return(_a0 []github.IssueAction, _a1 error) *MockClient_TrackFailureIssues_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_TrackFailureIssues_Call) RunAndReturn(run func(context.Context, []github.WorkflowStreak, github.IssueOptions) ([]github.IssueAction, error)) *MockClient_TrackFailureIssues_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockClient creates a new instance of MockClient. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockClient(t interface {
@@ -0,0 +1,29 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RateLimit describes the core API rate limit of the authenticated client
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// GetRateLimit retrieves the current core API rate limit. This call does not count against the limit.
+func (g *GitHubClient) GetRateLimit(ctx context.Context) (RateLimit, error) {
+	limits, _, err := g.client.RateLimit.Get(ctx)
+	if err != nil {
+		return RateLimit{}, fmt.Errorf("error getting rate limit: %v", err)
+	}
+
+	core := limits.GetCore()
+	return RateLimit{
+		Limit:     core.Limit,
+		Remaining: core.Remaining,
+		Reset:     core.Reset.Time,
+	}, nil
+}
@@ -0,0 +1,202 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// watermarkOverlap re-fetches a short window before each watermark so that runs
+// created just before the previous sync, but not yet listed by it, are not missed
+const watermarkOverlap = time.Hour
+
+// syncWorkers bounds how many repositories are synced concurrently
+const syncWorkers = 8
+
+// cutoffGranularity rounds the start of the window down, so that repeated scans within
+// the same hour send identical requests that the response cache can revalidate
+const cutoffGranularity = time.Hour
+
+// Watermark records the newest run seen for a repository
+type Watermark struct {
+	RunID     int64
+	CreatedAt time.Time
+	// CoveredFrom is the start of the window that has been scanned up to the watermark
+	CoveredFrom time.Time
+	// SyncedAt is when the repository was first scanned for the current window. A
+	// repository without runs resumes from there, so that the repeated request stays
+	// the same and can be answered from the response cache.
+	SyncedAt time.Time
+	// PendingFrom is the creation time of the oldest run that was still queued or in
+	// progress during the last scan. The next scan resumes no later than that, so the
+	// run is fetched once it completes, however long it takes.
+	PendingFrom time.Time
+}
+
+// resumeFrom returns when the next scan of the repository may start, or the zero time
+// if the watermark does not cover the window starting at cutoff
+func (w Watermark) resumeFrom(cutoff time.Time) time.Time {
+	if w.CoveredFrom.IsZero() || w.CoveredFrom.After(cutoff) {
+		return time.Time{}
+	}
+
+	since := w.CreatedAt
+	if since.IsZero() {
+		since = w.SyncedAt
+	}
+	if !w.PendingFrom.IsZero() && w.PendingFrom.Before(since) {
+		since = w.PendingFrom
+	}
+	since = since.Add(-watermarkOverlap)
+	if !since.After(cutoff) {
+		return time.Time{}
+	}
+	return since
+}
+
+// SyncRuns retrieves the completed workflow runs across repositories, whatever their
+// conclusion, fetching only runs newer than each repository's watermark. Repositories
+// without a watermark, or whose watermark does not cover the requested window, are
+// scanned in full; archived repositories with a covering watermark are skipped, since
+// they cannot run workflows. Repositories are scanned concurrently. The updated
+// watermarks are returned alongside the newly fetched runs.
+func (g *GitHubClient) SyncRuns(ctx context.Context, days int, watermarks map[string]Watermark) ([]RunSummary, map[string]Watermark, error) {
+	allRepos, err := g.listRepos(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cutoffTime := time.Now().AddDate(0, 0, -days).Truncate(cutoffGranularity)
+
+	type result struct {
+		runs []RunSummary
+		mark Watermark
+		ok   bool
+	}
+	results := make([]result, len(allRepos))
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, syncWorkers)
+	for i, repo := range allRepos {
+		mark, ok := watermarks[repo.GetName()]
+		if ok && repo.GetArchived() && !mark.resumeFrom(cutoffTime).IsZero() {
+			results[i] = result{mark: mark, ok: true}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			runs, updated, err := g.syncRepo(ctx, repo, mark, cutoffTime)
+			if err != nil {
+				// Keep the previous watermark so the next sync retries the same window
+				results[i] = result{mark: mark, ok: ok}
+				return
+			}
+			results[i] = result{runs: runs, mark: updated, ok: true}
+		}()
+	}
+	wg.Wait()
+
+	updated := make(map[string]Watermark, len(allRepos))
+	var summaries []RunSummary
+	for i, repo := range allRepos {
+		summaries = append(summaries, results[i].runs...)
+		if results[i].ok {
+			updated[repo.GetName()] = results[i].mark
+		}
+	}
+
+	return summaries, updated, nil
+}
+
+// syncRepo retrieves the completed runs of a repository newer than its watermark and
+// advances the watermark to the newest run seen, whatever its conclusion. Runs that have
+// not completed yet are left for a later scan, which resumes no later than the oldest of them.
+func (g *GitHubClient) syncRepo(ctx context.Context, repo *github.Repository, mark Watermark, cutoff time.Time) ([]RunSummary, Watermark, error) {
+	name := repo.GetName()
+	syncedAt := time.Now()
+
+	since := mark.resumeFrom(cutoff)
+	if since.IsZero() {
+		since = cutoff
+		mark = Watermark{}
+	}
+	if mark.CoveredFrom.IsZero() || cutoff.Before(mark.CoveredFrom) {
+		mark.CoveredFrom = cutoff
+	}
+
+	runs, err := g.listRunsSince(ctx, name, since)
+	if err != nil {
+		return nil, mark, err
+	}
+
+	// Pending runs are listed again by every scan until they complete
+	mark.PendingFrom = time.Time{}
+	summaries := make([]RunSummary, 0, len(runs))
+	for _, run := range runs {
+		if run.GetStatus() != "completed" {
+			if created := run.GetCreatedAt().Time; mark.PendingFrom.IsZero() || created.Before(mark.PendingFrom) {
+				mark.PendingFrom = created
+			}
+			continue
+		}
+
+		if run.GetID() > mark.RunID {
+			mark.RunID = run.GetID()
+		}
+		if run.GetCreatedAt().After(mark.CreatedAt) {
+			mark.CreatedAt = run.GetCreatedAt().Time
+		}
+
+		summary := newRunSummary(run)
+		summary.Repo = name
+		if summary.PRNumber != 0 {
+			// Pull requests embedded in runs only carry API URLs
+			summary.PRURL = fmt.Sprintf("%s/pull/%d", repo.GetHTMLURL(), summary.PRNumber)
+		}
+		summaries = append(summaries, summary)
+	}
+	if mark.SyncedAt.IsZero() {
+		mark.SyncedAt = syncedAt
+	}
+
+	return summaries, mark, nil
+}
+
+// listRunsSince retrieves all runs in a repository created at or after since, completed or not
+func (g *GitHubClient) listRunsSince(ctx context.Context, repo string, since time.Time) ([]*github.WorkflowRun, error) {
+	opts := &github.ListWorkflowRunsOptions{
+		Created: ">=" + since.UTC().Format(time.RFC3339),
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var all []*github.WorkflowRun
+	for {
+		runs, resp, err := g.client.Actions.ListRepositoryWorkflowRuns(ctx, g.owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting workflow runs for %s: %v", repo, err)
+		}
+
+		all = append(all, runs.WorkflowRuns...)
+
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// ListCompletedRuns retrieves every completed workflow run created within the window across repositories
+func (g *GitHubClient) ListCompletedRuns(ctx context.Context, days int) ([]RunSummary, error) {
+	runs, _, err := g.SyncRuns(ctx, days, nil)
+	return runs, err
+}
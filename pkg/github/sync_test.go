@@ -0,0 +1,126 @@
+package github_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncRuns(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	newest := now.Add(-10 * time.Minute)
+
+	var mu sync.Mutex
+	created := make(map[string]time.Time)
+	failing := false
+	// A long matrix run created before the newest run completes later
+	pending := now.Add(-5 * time.Hour)
+	pendingStatus := "in_progress"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v3/orgs/acme/repos" {
+			fmt.Fprint(w, `[
+				{"name": "api", "html_url": "https://github.com/acme/api"},
+				{"name": "quiet", "html_url": "https://github.com/acme/quiet"},
+				{"name": "old", "html_url": "https://github.com/acme/old", "archived": true}
+			]`)
+			return
+		}
+
+		repo := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v3/repos/acme/"), "/actions/runs")
+		// Runs are listed whatever their status, so that pending runs are noticed
+		assert.Empty(t, r.URL.Query().Get("status"))
+		since, err := time.Parse(time.RFC3339, strings.TrimPrefix(r.URL.Query().Get("created"), ">="))
+		require.NoError(t, err)
+
+		mu.Lock()
+		created[repo] = since
+		mu.Unlock()
+
+		if repo != "api" {
+			fmt.Fprint(w, `{"total_count": 0, "workflow_runs": []}`)
+			return
+		}
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// The newest run is a success on the default branch, the older one a failure on a PR
+		fmt.Fprintf(w, `{"total_count": 3, "workflow_runs": [
+			{"id": 12, "name": "CI", "status": "completed", "conclusion": "success", "head_branch": "main", "created_at": %q},
+			{"id": 11, "name": "CI", "status": "completed", "conclusion": "failure", "head_branch": "feature", "created_at": %q,
+			 "pull_requests": [{"number": 5}]},
+			{"id": 10, "name": "Matrix", "status": %q, "conclusion": "failure", "head_branch": "main", "created_at": %q}
+		]}`, newest.Format(time.RFC3339), now.Add(-2*time.Hour).Format(time.RFC3339), pendingStatus, pending.Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	client, err := github.NewClient("token", "acme", github.WithBaseURL(server.URL+"/api/v3"))
+	require.NoError(t, err)
+	ctx := context.Background()
+	// The window starts on the hour, so repeated scans send the same request
+	cutoff := now.AddDate(0, 0, -1).Truncate(time.Hour)
+
+	// Without watermarks every repository is scanned over the whole window
+	runs, marks, err := client.SyncRuns(ctx, 1, nil)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "success", runs[0].Conclusion)
+	assert.Equal(t, "https://github.com/acme/api/pull/5", runs[1].PRURL)
+	assert.Len(t, created, 3)
+	for repo, since := range created {
+		assert.WithinDuration(t, cutoff, since, time.Minute, repo)
+	}
+
+	// The watermark follows the newest run, not the newest failure, and remembers the
+	// run that has not completed yet
+	assert.Equal(t, int64(12), marks["api"].RunID)
+	assert.True(t, newest.Equal(marks["api"].CreatedAt))
+	assert.True(t, pending.Equal(marks["api"].PendingFrom))
+	assert.True(t, marks["quiet"].CreatedAt.IsZero())
+	assert.WithinDuration(t, now, marks["quiet"].SyncedAt, time.Minute)
+
+	// The next sync resumes from the watermarks, even for repositories without runs,
+	// and skips archived repositories. The pending run is listed again until it completes,
+	// long after the newest run passed it.
+	created = make(map[string]time.Time)
+	pendingStatus = "completed"
+	runs, next, err := client.SyncRuns(ctx, 1, marks)
+	require.NoError(t, err)
+	assert.True(t, pending.Add(-time.Hour).Equal(created["api"]))
+	require.Len(t, runs, 3)
+	assert.Equal(t, "Matrix", runs[2].Workflow)
+	assert.True(t, next["api"].PendingFrom.IsZero())
+	assert.WithinDuration(t, now.Add(-time.Hour), created["quiet"], time.Minute)
+	assert.NotContains(t, created, "old")
+	assert.Equal(t, marks["old"], next["old"])
+
+	// Once nothing is pending the sync resumes from the newest run again, and a
+	// repository without runs keeps resuming from the same point
+	quiet := created["quiet"]
+	_, _, err = client.SyncRuns(ctx, 1, next)
+	require.NoError(t, err)
+	assert.True(t, newest.Add(-time.Hour).Equal(created["api"]))
+	assert.True(t, quiet.Equal(created["quiet"]))
+
+	// A wider window than the watermarks cover is scanned in full
+	created = make(map[string]time.Time)
+	_, _, err = client.SyncRuns(ctx, 7, marks)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.AddDate(0, 0, -7).Truncate(time.Hour), created["api"], time.Minute)
+	assert.Contains(t, created, "old")
+
+	// A repository that cannot be read keeps its previous watermark
+	failing = true
+	_, next, err = client.SyncRuns(ctx, 1, marks)
+	require.NoError(t, err)
+	assert.Equal(t, marks["api"], next["api"])
+}
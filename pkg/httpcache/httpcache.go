@@ -0,0 +1,390 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	entrySuffix = ".json"
+	statsFile   = "stats.json"
+
+	// pruneEvery is how many responses are stored between two evictions
+	pruneEvery = 100
+)
+
+// Stats describes the contents and effectiveness of the cache
+type Stats struct {
+	Entries int   `json:"-"`
+	Bytes   int64 `json:"-"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+// entry is a cached response stored on disk
+type entry struct {
+	URL        string      `json:"url"`
+	ETag       string      `json:"etag"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Transport is an http.RoundTripper that caches GET responses on disk by ETag and
+// revalidates them with conditional requests. GitHub does not count 304 responses
+// against the rate limit, so repeated scans of unchanged resources are free.
+type Transport struct {
+	// Base is the underlying transport; http.DefaultTransport is used when nil
+	Base http.RoundTripper
+	// Dir is the directory cached responses are stored in
+	Dir string
+	// MaxBytes caps the size of the cache; the least recently used entries are evicted
+	// beyond it. Zero means no limit.
+	MaxBytes int64
+	// MaxAge evicts entries that have not been used for longer. Zero means no limit.
+	MaxAge time.Duration
+
+	mu sync.Mutex
+	// hits and misses are counted in memory until Flush adds them to the stored statistics
+	hits   int64
+	misses int64
+	// stored counts the responses stored since the last eviction
+	stored int
+}
+
+// DefaultDir returns the default cache directory, following the XDG base directory spec
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding cache directory: %v", err)
+	}
+	return filepath.Join(dir, "gh-workflow-monitor", "http"), nil
+}
+
+// New creates a caching transport storing responses in dir
+func New(dir string, base http.RoundTripper) *Transport {
+	return &Transport{Base: base, Dir: dir}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base().RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	cached := t.load(key)
+
+	if cached != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		t.record(true)
+		t.touch(key)
+		return cached.response(req, resp.Header), nil
+	}
+
+	t.record(false)
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.store(key, &entry{
+		URL:        req.URL.String(),
+		ETag:       etag,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	})
+
+	return resp, nil
+}
+
+// Stats returns the current cache statistics
+func (t *Transport) Stats() (Stats, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.readStats()
+	stats.Hits += t.hits
+	stats.Misses += t.misses
+
+	files, err := os.ReadDir(t.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, fmt.Errorf("error reading cache directory: %v", err)
+	}
+
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), entrySuffix) || file.Name() == statsFile {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		stats.Entries++
+		stats.Bytes += info.Size()
+	}
+
+	return stats, nil
+}
+
+// Clear removes every cached response and resets the statistics
+func (t *Transport) Clear() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := os.RemoveAll(t.Dir); err != nil {
+		return fmt.Errorf("error clearing cache: %v", err)
+	}
+	t.hits, t.misses = 0, 0
+	return nil
+}
+
+// Flush adds the hits and misses counted since the last flush to the stored statistics
+// and evicts expired entries. It is meant to be called once before exiting.
+func (t *Transport) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.prune(); err != nil {
+		return err
+	}
+	if t.hits == 0 && t.misses == 0 {
+		return nil
+	}
+
+	stats := t.readStats()
+	stats.Hits += t.hits
+	stats.Misses += t.misses
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(t.Dir, statsFile, data); err != nil {
+		return fmt.Errorf("error saving cache statistics: %v", err)
+	}
+
+	t.hits, t.misses = 0, 0
+	return nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// load reads a cached entry, treating unreadable entries as missing
+func (t *Transport) load(key string) *entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := os.ReadFile(filepath.Join(t.Dir, key+entrySuffix))
+	if err != nil {
+		return nil
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.ETag == "" {
+		return nil
+	}
+	return &e
+}
+
+// store writes an entry to disk, evicting old entries every so often; failures only
+// cost a future cache miss
+func (t *Transport) store(key string, e *entry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	_ = writeFile(t.Dir, key+entrySuffix, data)
+
+	if t.stored++; t.stored >= pruneEvery {
+		_ = t.prune()
+	}
+}
+
+// touch marks an entry as recently used
+func (t *Transport) touch(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	_ = os.Chtimes(filepath.Join(t.Dir, key+entrySuffix), now, now)
+}
+
+// Prune evicts the entries that have not been used within MaxAge, then the least
+// recently used entries until the cache fits in MaxBytes
+func (t *Transport) Prune() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.prune()
+}
+
+func (t *Transport) prune() error {
+	t.stored = 0
+	if t.MaxBytes <= 0 && t.MaxAge <= 0 {
+		return nil
+	}
+
+	files, err := os.ReadDir(t.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error reading cache directory: %v", err)
+	}
+
+	type cached struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []cached
+	var total int64
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), entrySuffix) || file.Name() == statsFile {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, cached{filepath.Join(t.Dir, file.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	// Least recently used first
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+
+	expired := time.Now().Add(-t.MaxAge)
+	for _, e := range entries {
+		stale := t.MaxAge > 0 && e.modTime.Before(expired)
+		if !stale && (t.MaxBytes <= 0 || total <= t.MaxBytes) {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error evicting cache entry: %v", err)
+		}
+		total -= e.size
+	}
+
+	return nil
+}
+
+// record counts a hit or miss in memory
+func (t *Transport) record(hit bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if hit {
+		t.hits++
+	} else {
+		t.misses++
+	}
+}
+
+func (t *Transport) readStats() Stats {
+	var stats Stats
+	data, err := os.ReadFile(filepath.Join(t.Dir, statsFile))
+	if err == nil {
+		_ = json.Unmarshal(data, &stats)
+	}
+	return stats
+}
+
+// response rebuilds an HTTP response from a cached entry, taking fresh headers
+// (such as rate limit information) from the revalidation response
+func (e *entry) response(req *http.Request, fresh http.Header) *http.Response {
+	header := e.Header.Clone()
+	for name, values := range fresh {
+		header[name] = values
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cacheKey identifies a request by its URL and Accept header. The credentials are left
+// out so that refreshed tokens and short-lived app installation tokens keep using the
+// cache. Every cached response is revalidated with the current credentials, so a token
+// without access to a resource gets an error from GitHub rather than the cached body.
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	h.Write([]byte(req.URL.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(req.Header.Get("Accept")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeFile atomically writes a file in dir
+func writeFile(dir, name string, data []byte) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, name+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
@@ -0,0 +1,204 @@
+package httpcache_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/httpcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Authorization") == "revoked" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		etag := `"v1"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("X-RateLimit-Remaining", "4998")
+		_, _ = io.WriteString(w, `{"hello":"world"}`)
+	}))
+	defer server.Close()
+
+	cache := httpcache.New(t.TempDir(), nil)
+	client := &http.Client{Transport: cache}
+
+	get := func(token string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/repos", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", token)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	read := func(resp *http.Response) string {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	// First request populates the cache
+	resp := get("token-a")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"hello":"world"}`, read(resp))
+
+	// Second request is revalidated and served from the cache
+	resp = get("token-a")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "4999", resp.Header.Get("X-RateLimit-Remaining"))
+	assert.Equal(t, `{"hello":"world"}`, read(resp))
+	assert.Equal(t, int32(1), notModified.Load())
+
+	// A refreshed token keeps using the cache
+	resp = get("token-b")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"hello":"world"}`, read(resp))
+	assert.Equal(t, int32(2), notModified.Load())
+
+	// A token that is refused never sees the cached body
+	resp = get("revoked")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, int32(4), requests.Load())
+
+	stats, err := cache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Entries)
+	assert.Positive(t, stats.Bytes)
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+
+	// Counters are kept in memory until they are flushed
+	stored, err := httpcache.New(cache.Dir, nil).Stats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stored.Hits)
+
+	require.NoError(t, cache.Flush())
+	stored, err = httpcache.New(cache.Dir, nil).Stats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stored.Hits)
+	assert.Equal(t, int64(2), stored.Misses)
+
+	// Flushing again does not count the same requests twice
+	require.NoError(t, cache.Flush())
+	stats, err = cache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Hits)
+
+	require.NoError(t, cache.Clear())
+
+	stats, err = cache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, httpcache.Stats{}, stats)
+
+	// After clearing, the next request is a full fetch again
+	resp = get("token-a")
+	assert.Equal(t, `{"hello":"world"}`, read(resp))
+	assert.Equal(t, int32(2), notModified.Load())
+}
+
+func TestTransportSkipsUncacheableRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	cache := httpcache.New(t.TempDir(), nil)
+	client := &http.Client{Transport: cache}
+
+	resp, err := client.Post(server.URL, "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	stats, err := cache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Entries)
+	assert.Equal(t, int64(0), stats.Misses)
+}
+
+func TestTransportEvictsEntries(t *testing.T) {
+	var revalidated atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + r.URL.Path + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			revalidated.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	cache := httpcache.New(t.TempDir(), nil)
+	client := &http.Client{Transport: cache}
+
+	get := func(path string) {
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// backdate marks every cached entry as last used the given duration ago
+	backdate := func(d time.Duration) {
+		paths, err := filepath.Glob(filepath.Join(cache.Dir, "*.json"))
+		require.NoError(t, err)
+		for _, path := range paths {
+			require.NoError(t, os.Chtimes(path, time.Now().Add(-d), time.Now().Add(-d)))
+		}
+	}
+
+	get("/a")
+	get("/b")
+	backdate(2 * time.Hour)
+
+	// A revalidated entry counts as recently used, so the other one is evicted first
+	get("/a")
+	assert.Equal(t, int32(1), revalidated.Load())
+	stats, err := cache.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.Entries)
+
+	cache.MaxBytes = stats.Bytes - 1
+	require.NoError(t, cache.Prune())
+	stats, err = cache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Entries)
+
+	get("/a")
+	assert.Equal(t, int32(2), revalidated.Load())
+	get("/b")
+	assert.Equal(t, int32(2), revalidated.Load())
+
+	// Entries unused for longer than the maximum age are evicted on flush
+	cache.MaxBytes = 0
+	cache.MaxAge = time.Hour
+	backdate(2 * time.Hour)
+	get("/b")
+	require.NoError(t, cache.Flush())
+	stats, err = cache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Entries)
+
+	get("/a")
+	assert.Equal(t, int32(3), revalidated.Load())
+}
@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// Registry holds a set of metrics and renders them in the Prometheus text exposition format
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*Vec
+}
+
+// Vec is a metric with zero or more labels
+type Vec struct {
+	registry   *Registry
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+	values     map[string]*sample
+}
+
+type sample struct {
+	labels []string
+	value  float64
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewGauge registers a gauge with the given label names
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Vec {
+	return r.register(name, help, TypeGauge, labelNames)
+}
+
+// NewCounter registers a counter with the given label names
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Vec {
+	return r.register(name, help, TypeCounter, labelNames)
+}
+
+func (r *Registry) register(name, help, metricType string, labelNames []string) *Vec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v := &Vec{
+		registry:   r,
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		values:     make(map[string]*sample),
+	}
+	r.metrics = append(r.metrics, v)
+	return v
+}
+
+// Set sets the value for the given label values
+func (v *Vec) Set(value float64, labels ...string) {
+	v.registry.mu.Lock()
+	defer v.registry.mu.Unlock()
+
+	v.get(labels).value = value
+}
+
+// Add adds to the value for the given label values
+func (v *Vec) Add(value float64, labels ...string) {
+	v.registry.mu.Lock()
+	defer v.registry.mu.Unlock()
+
+	v.get(labels).value += value
+}
+
+// Reset removes every labelled value, e.g. before repopulating a gauge after a scan
+func (v *Vec) Reset() {
+	v.registry.mu.Lock()
+	defer v.registry.mu.Unlock()
+
+	v.values = make(map[string]*sample)
+}
+
+// Value returns the current value for the given label values
+func (v *Vec) Value(labels ...string) float64 {
+	v.registry.mu.Lock()
+	defer v.registry.mu.Unlock()
+
+	if s, ok := v.values[strings.Join(labels, "\x00")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (v *Vec) get(labels []string) *sample {
+	if len(labels) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d labels, got %d", v.name, len(v.labelNames), len(labels)))
+	}
+
+	key := strings.Join(labels, "\x00")
+	s, ok := v.values[key]
+	if !ok {
+		s = &sample{labels: append([]string(nil), labels...)}
+		v.values[key] = s
+	}
+	return s
+}
+
+// WriteText writes every metric in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	for _, v := range r.metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", v.name, v.metricType)
+
+		keys := make([]string, 0, len(v.values))
+		for key := range v.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			s := v.values[key]
+			b.WriteString(v.name)
+			if len(v.labelNames) > 0 {
+				b.WriteByte('{')
+				for i, name := range v.labelNames {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", name, escapeLabel(s.labels[i]))
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(formatValue(s.value))
+			b.WriteByte('\n')
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP serves the metrics of the registry
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WriteText(w)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,57 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := metrics.NewRegistry()
+	failing := registry.NewGauge("failing", "Failing workflows", "repo")
+	runs := registry.NewCounter("runs_total", "Runs\nobserved", "repo", "conclusion")
+	up := registry.NewGauge("up", "Whether the exporter is up")
+
+	failing.Set(2, "repo-b")
+	failing.Set(1, `repo"a`)
+	runs.Add(1, "repo", "failure")
+	runs.Add(2, "repo", "failure")
+	up.Set(1)
+
+	var b strings.Builder
+	require.NoError(t, registry.WriteText(&b))
+
+	assert.Equal(t, `# HELP failing Failing workflows
+# TYPE failing gauge
+failing{repo="repo\"a"} 1
+failing{repo="repo-b"} 2
+# HELP runs_total Runs\nobserved
+# TYPE runs_total counter
+runs_total{repo="repo",conclusion="failure"} 3
+# HELP up Whether the exporter is up
+# TYPE up gauge
+up 1
+`, b.String())
+
+	assert.Equal(t, 3.0, runs.Value("repo", "failure"))
+
+	failing.Reset()
+	assert.Equal(t, 0.0, failing.Value("repo-b"))
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.NotContains(t, rec.Body.String(), "failing{")
+	assert.Contains(t, rec.Body.String(), "up 1")
+}
+
+func TestLabelCountMismatch(t *testing.T) {
+	registry := metrics.NewRegistry()
+	gauge := registry.NewGauge("failing", "Failing workflows", "repo")
+
+	assert.Panics(t, func() { gauge.Set(1) })
+}
@@ -0,0 +1,283 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+)
+
+// Event types
+const (
+	EventFailure  = "failure"
+	EventResolved = "resolved"
+)
+
+// requestTimeout bounds every request sent by a notifier, so that an unresponsive
+// service cannot hold up the events queued behind it
+const requestTimeout = 10 * time.Second
+
+// defaultClient sends the requests of notifiers without their own client
+var defaultClient = &http.Client{Timeout: requestTimeout}
+
+// Event is a change in the health of a workflow that notifiers announce
+type Event struct {
+	Type string
+	// Failure is the failed run, or for resolved events the run that was resolved
+	Failure github.WorkflowFailure
+	// Resolution is the successful run that resolved the failure, for resolved events
+	Resolution *github.RunSummary
+}
+
+// Notifier announces events to an external service
+type Notifier interface {
+	// Name identifies the notifier in the notification state
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+// Notification records a failed run that a notifier has handled
+type Notification struct {
+	RunID      int64     `json:"run_id"`
+	Repo       string    `json:"repo"`
+	Workflow   string    `json:"workflow"`
+	Branch     string    `json:"branch,omitempty"`
+	Conclusion string    `json:"conclusion"`
+	HeadSHA    string    `json:"head_sha"`
+	Actor      string    `json:"actor,omitempty"`
+	PRNumber   int       `json:"pr_number,omitempty"`
+	PRURL      string    `json:"pr_url,omitempty"`
+	URL        string    `json:"url"`
+	StartedAt  time.Time `json:"started_at"`
+	// AnnouncedAt is when the failure was announced, or suppressed while snoozed
+	AnnouncedAt time.Time `json:"announced_at"`
+	// Snoozed is set when the failure was not announced because it was snoozed
+	Snoozed    bool      `json:"snoozed,omitempty"`
+	ResolvedAt time.Time `json:"resolved_at,omitempty"`
+}
+
+// Resolved reports whether the workflow has recovered from the failure
+func (n Notification) Resolved() bool {
+	return !n.ResolvedAt.IsZero()
+}
+
+// State records which failures each notifier has announced and which workflows are snoozed
+type State interface {
+	Notified(notifier string, runID int64) (bool, error)
+	MarkNotified(notifier string, n Notification) error
+	Unresolved(notifier string) ([]Notification, error)
+	MarkResolved(notifier string, runID int64, at time.Time) error
+	Snoozed(repo, workflow string, at time.Time) (bool, error)
+}
+
+// Announce sends every failure the notifier has not announced yet, oldest first, and
+// returns how many were sent. Failures of snoozed workflows are recorded without being
+// sent; failures that could not be sent are retried on the next call.
+func Announce(ctx context.Context, notifier Notifier, state State, failures []github.WorkflowFailure) (int, error) {
+	sorted := make([]github.WorkflowFailure, len(failures))
+	copy(sorted, failures)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartedAt.Before(sorted[j].StartedAt)
+	})
+
+	sent := 0
+	var errs []error
+	for _, failure := range sorted {
+		notified, err := state.Notified(notifier.Name(), failure.RunID)
+		if err != nil {
+			return sent, err
+		}
+		if notified {
+			continue
+		}
+
+		now := time.Now()
+		snoozed, err := state.Snoozed(failure.Repo, failure.Workflow, now)
+		if err != nil {
+			return sent, err
+		}
+
+		if !snoozed {
+			if err := notifier.Notify(ctx, Event{Type: EventFailure, Failure: failure}); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			sent++
+		}
+
+		n := newNotification(failure, now)
+		n.Snoozed = snoozed
+		if err := state.MarkNotified(notifier.Name(), n); err != nil {
+			return sent, err
+		}
+	}
+
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("failed to send %d of %d notifications: %v", len(errs), len(errs)+sent, errs[0])
+	}
+	return sent, nil
+}
+
+// Resolve marks every announced failure whose workflow has since succeeded on the same
+// branch as resolved, and returns how many resolved events were sent. One event is sent
+// per workflow and branch, for its latest resolved failure, however many failures it
+// resolves. Failures that were snoozed when they happened, or whose workflow is snoozed
+// now, are resolved silently.
+func Resolve(ctx context.Context, notifier Notifier, state State, runs []github.RunSummary) (int, error) {
+	unresolved, err := state.Unresolved(notifier.Name())
+	if err != nil {
+		return 0, err
+	}
+
+	// Group the failures each success resolves by workflow and branch, in the order the
+	// workflows first appear
+	type group struct {
+		resolved   []Notification
+		latest     Notification
+		resolution *github.RunSummary
+		announced  bool
+	}
+	type workflowKey struct{ repo, workflow, branch string }
+	groups := make(map[workflowKey]*group)
+	var order []workflowKey
+	for _, n := range unresolved {
+		resolution := firstSuccessAfter(runs, n)
+		if resolution == nil {
+			continue
+		}
+
+		key := workflowKey{n.Repo, n.Workflow, n.Branch}
+		g, ok := groups[key]
+		if !ok {
+			g = &group{}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.resolved = append(g.resolved, n)
+		if g.resolution == nil || n.StartedAt.After(g.latest.StartedAt) {
+			g.latest, g.resolution = n, resolution
+		}
+		g.announced = g.announced || !n.Snoozed
+	}
+
+	sent := 0
+	var errs []error
+	for _, key := range order {
+		g := groups[key]
+
+		now := time.Now()
+		snoozed, err := state.Snoozed(key.repo, key.workflow, now)
+		if err != nil {
+			return sent, err
+		}
+
+		if g.announced && !snoozed {
+			event := Event{Type: EventResolved, Failure: newFailure(g.latest), Resolution: g.resolution}
+			if err := notifier.Notify(ctx, event); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			sent++
+		}
+
+		for _, n := range g.resolved {
+			if err := state.MarkResolved(notifier.Name(), n.RunID, now); err != nil {
+				return sent, err
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("failed to send %d of %d notifications: %v", len(errs), len(errs)+sent, errs[0])
+	}
+	return sent, nil
+}
+
+// Sink announces each failed workflow run it observes through a notifier, such as
+// failures delivered by webhooks. Runs the notifier has already announced, e.g. through
+// the notify commands, are skipped. Failed check suites are skipped too: the notification
+// state tracks workflow runs, and check suites never appear among the runs that resolve
+// a failure.
+type Sink struct {
+	ctx      context.Context
+	notifier Notifier
+	state    State
+
+	// mu serializes announcements so that a redelivered event is not announced twice
+	mu sync.Mutex
+}
+
+// NewSink creates a sink announcing failures through notifier
+func NewSink(ctx context.Context, notifier Notifier, state State) *Sink {
+	return &Sink{ctx: ctx, notifier: notifier, state: state}
+}
+
+// Observe implements webhook.Sink
+func (s *Sink) Observe(failure github.WorkflowFailure) {
+	if failure.RunID == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := Announce(s.ctx, s.notifier, s.state, []github.WorkflowFailure{failure}); err != nil {
+		log.Printf("Failed to announce %s / %s through %s: %v", failure.Repo, failure.Workflow, s.notifier.Name(), err)
+	}
+}
+
+// firstSuccessAfter finds the earliest successful run of the failed workflow on the
+// same branch that started after the failure
+func firstSuccessAfter(runs []github.RunSummary, n Notification) *github.RunSummary {
+	var first *github.RunSummary
+	for i, run := range runs {
+		if !run.Succeeded() || run.Repo != n.Repo || run.Workflow != n.Workflow || run.Branch != n.Branch {
+			continue
+		}
+		if !run.CreatedAt.After(n.StartedAt) {
+			continue
+		}
+		if first == nil || run.CreatedAt.Before(first.CreatedAt) {
+			first = &runs[i]
+		}
+	}
+	return first
+}
+
+func newNotification(failure github.WorkflowFailure, at time.Time) Notification {
+	return Notification{
+		RunID:       failure.RunID,
+		Repo:        failure.Repo,
+		Workflow:    failure.Workflow,
+		Branch:      failure.Branch,
+		Conclusion:  failure.Conclusion,
+		HeadSHA:     failure.HeadSHA,
+		Actor:       failure.Actor,
+		PRNumber:    failure.PRNumber,
+		PRURL:       failure.PRURL,
+		URL:         failure.URL,
+		StartedAt:   failure.StartedAt,
+		AnnouncedAt: at,
+	}
+}
+
+func newFailure(n Notification) github.WorkflowFailure {
+	return github.WorkflowFailure{
+		RunID:      n.RunID,
+		Repo:       n.Repo,
+		PRNumber:   n.PRNumber,
+		Workflow:   n.Workflow,
+		Conclusion: n.Conclusion,
+		HeadSHA:    n.HeadSHA,
+		Branch:     n.Branch,
+		Actor:      n.Actor,
+		StartedAt:  n.StartedAt,
+		URL:        n.URL,
+		PRURL:      n.PRURL,
+	}
+}
@@ -0,0 +1,141 @@
+package notify_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	stub, server := newSlackStub(t)
+	slack := notify.NewSlack(server.URL, nil)
+	state := newMemoryState()
+
+	now := time.Now()
+	failures := []github.WorkflowFailure{
+		{RunID: 1, Repo: "api", Workflow: "CI", Branch: "feature", StartedAt: now.Add(-3 * time.Hour)},
+		{RunID: 2, Repo: "api", Workflow: "Lint", Branch: "feature", StartedAt: now.Add(-3 * time.Hour)},
+	}
+	_, err := notify.Announce(context.Background(), slack, state, failures)
+	require.NoError(t, err)
+
+	runs := []github.RunSummary{
+		// Successes on other branches, or before the failure, do not resolve it
+		{ID: 10, Repo: "api", Workflow: "CI", Branch: "main", Conclusion: "success", CreatedAt: now.Add(-time.Hour)},
+		{ID: 11, Repo: "api", Workflow: "Lint", Branch: "feature", Conclusion: "success", CreatedAt: now.Add(-4 * time.Hour)},
+		{ID: 12, Repo: "api", Workflow: "CI", Branch: "feature", Conclusion: "failure", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: 14, Repo: "api", Workflow: "CI", Branch: "feature", Conclusion: "success", CreatedAt: now.Add(-30 * time.Minute), URL: "https://github.com/acme/api/actions/runs/14"},
+		{ID: 13, Repo: "api", Workflow: "CI", Branch: "feature", Conclusion: "success", CreatedAt: now.Add(-time.Hour), URL: "https://github.com/acme/api/actions/runs/13"},
+	}
+
+	sent, err := notify.Resolve(context.Background(), slack, state, runs)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	require.Len(t, stub.messages["/"], 3)
+	assert.Equal(t, "CI recovered in api: https://github.com/acme/api/actions/runs/13", stub.messages["/"][2]["text"])
+
+	unresolved, err := state.Unresolved(slack.Name())
+	require.NoError(t, err)
+	if assert.Len(t, unresolved, 1) {
+		assert.Equal(t, int64(2), unresolved[0].RunID)
+	}
+
+	// A failure is only resolved once
+	sent, err = notify.Resolve(context.Background(), slack, state, runs)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
+
+func TestResolveRepeatedFailures(t *testing.T) {
+	stub, server := newSlackStub(t)
+	slack := notify.NewSlack(server.URL, nil)
+	state := newMemoryState()
+
+	now := time.Now()
+	failures := []github.WorkflowFailure{
+		{RunID: 1, Repo: "api", Workflow: "CI", Branch: "main", StartedAt: now.Add(-3 * time.Hour)},
+		{RunID: 2, Repo: "api", Workflow: "CI", Branch: "main", StartedAt: now.Add(-2 * time.Hour), URL: "https://github.com/acme/api/actions/runs/2"},
+	}
+	sent, err := notify.Announce(context.Background(), slack, state, failures)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	runs := []github.RunSummary{
+		{ID: 3, Repo: "api", Workflow: "CI", Branch: "main", Conclusion: "success", CreatedAt: now.Add(-time.Hour), URL: "https://github.com/acme/api/actions/runs/3"},
+	}
+
+	// One recovery covers every failure of the workflow on the branch
+	sent, err = notify.Resolve(context.Background(), slack, state, runs)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, stub.messages["/"], 3)
+	assert.Equal(t, "CI recovered in api: https://github.com/acme/api/actions/runs/3", stub.messages["/"][2]["text"])
+
+	unresolved, err := state.Unresolved(slack.Name())
+	require.NoError(t, err)
+	assert.Empty(t, unresolved)
+}
+
+func TestSnooze(t *testing.T) {
+	stub, server := newSlackStub(t)
+	slack := notify.NewSlack(server.URL, nil)
+	state := newMemoryState()
+
+	now := time.Now()
+	state.snoozed["api/CI"] = true
+
+	failures := []github.WorkflowFailure{
+		{RunID: 1, Repo: "api", Workflow: "CI", Branch: "main", StartedAt: now.Add(-time.Hour)},
+		{RunID: 2, Repo: "api", Workflow: "Lint", Branch: "main", StartedAt: now.Add(-time.Hour)},
+	}
+	sent, err := notify.Announce(context.Background(), slack, state, failures)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	// Snoozed failures are not announced once the snooze ends either
+	delete(state.snoozed, "api/CI")
+	sent, err = notify.Announce(context.Background(), slack, state, failures)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	// ...and resolve silently
+	runs := []github.RunSummary{
+		{ID: 3, Repo: "api", Workflow: "CI", Branch: "main", Conclusion: "success", CreatedAt: now},
+	}
+	sent, err = notify.Resolve(context.Background(), slack, state, runs)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Len(t, stub.messages["/"], 1)
+}
+
+func TestSink(t *testing.T) {
+	stub, server := newSlackStub(t)
+	slack := notify.NewSlack(server.URL, nil)
+	state := newMemoryState()
+
+	now := time.Now()
+	state.snoozed["api/Lint"] = true
+	sink := notify.NewSink(context.Background(), slack, state)
+
+	failure := github.WorkflowFailure{RunID: 1, Repo: "api", Workflow: "CI", Branch: "main", StartedAt: now}
+	sink.Observe(failure)
+	// A redelivered event is announced once
+	sink.Observe(failure)
+	sink.Observe(github.WorkflowFailure{RunID: 2, Repo: "api", Workflow: "Lint", Branch: "main", StartedAt: now})
+	// Check suites are not tracked by the notification state
+	sink.Observe(github.WorkflowFailure{CheckSuiteID: 1, Repo: "web", Workflow: "CircleCI Checks", StartedAt: now})
+
+	require.Len(t, stub.messages["/"], 1)
+	assert.Equal(t, "CI failed in api: ", stub.messages["/"][0]["text"])
+
+	// Runs the sink announced are not announced again by the notify commands
+	sent, err := notify.Announce(context.Background(), slack, state, []github.WorkflowFailure{failure})
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
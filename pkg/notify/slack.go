@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Slack posts Block Kit messages to Slack incoming webhooks
+type Slack struct {
+	// URL is the incoming webhook used for repositories without a route
+	URL string
+	// Routes maps repository names to the incoming webhook of their channel
+	Routes map[string]string
+	// Client is the HTTP client used to post messages; a client with a timeout is used when nil
+	Client *http.Client
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string         `json:"type"`
+	Text     *slackText     `json:"text,omitempty"`
+	Fields   []slackText    `json:"fields,omitempty"`
+	Elements []slackElement `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackElement struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+	URL  string     `json:"url,omitempty"`
+}
+
+// NewSlack creates a Slack notifier posting to url unless a route matches the repository
+func NewSlack(url string, routes map[string]string) *Slack {
+	return &Slack{URL: url, Routes: routes}
+}
+
+// Name implements Notifier
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+// Notify implements Notifier. Events in repositories that are neither routed
+// nor covered by a default webhook are dropped.
+func (s *Slack) Notify(ctx context.Context, event Event) error {
+	failure := event.Failure
+	url := s.URL
+	if route, ok := s.Routes[failure.Repo]; ok {
+		url = route
+	}
+	if url == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(newSlackMessage(event))
+	if err != nil {
+		return fmt.Errorf("error encoding Slack message: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating Slack request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting to Slack: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("error posting to Slack: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// newSlackMessage builds the Block Kit message announcing an event
+func newSlackMessage(event Event) slackMessage {
+	failure := event.Failure
+	title := fmt.Sprintf("%s failed in %s", failure.Workflow, failure.Repo)
+	link := failure.URL
+	if event.Type == EventResolved {
+		title = fmt.Sprintf("%s recovered in %s", failure.Workflow, failure.Repo)
+		if event.Resolution != nil {
+			link = event.Resolution.URL
+		}
+	}
+
+	// Runs outside a pull request, e.g. on the default branch, show their branch instead
+	source := "*Branch*\n" + failure.Branch
+	if failure.PRNumber != 0 {
+		source = fmt.Sprintf("*Pull request*\n<%s|#%d>", failure.PRURL, failure.PRNumber)
+	} else if failure.Branch == "" {
+		source = "*Pull request*\n-"
+	}
+	author := "-"
+	if failure.Actor != "" {
+		author = failure.Actor
+	}
+
+	return slackMessage{
+		Text: fmt.Sprintf("%s: %s", title, link),
+		Blocks: []slackBlock{
+			{
+				Type: "header",
+				Text: &slackText{Type: "plain_text", Text: title},
+			},
+			{
+				Type: "section",
+				Fields: []slackText{
+					{Type: "mrkdwn", Text: "*Repository*\n" + failure.Repo},
+					{Type: "mrkdwn", Text: source},
+					{Type: "mrkdwn", Text: "*Workflow*\n" + failure.Workflow},
+					{Type: "mrkdwn", Text: "*Author*\n" + author},
+					{Type: "mrkdwn", Text: "*Conclusion*\n" + failure.Conclusion},
+					{Type: "mrkdwn", Text: "*Started*\n" + failure.StartedAt.Format(time.RFC1123)},
+				},
+			},
+			{
+				Type: "actions",
+				Elements: []slackElement{
+					{
+						Type: "button",
+						Text: &slackText{Type: "plain_text", Text: "View run"},
+						URL:  link,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,230 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slackStub is a local stand-in for Slack incoming webhooks
+type slackStub struct {
+	mu       sync.Mutex
+	messages map[string][]map[string]any
+	status   int
+}
+
+func newSlackStub(t *testing.T) (*slackStub, *httptest.Server) {
+	stub := &slackStub{messages: make(map[string][]map[string]any), status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, "invalid_payload", http.StatusBadRequest)
+			return
+		}
+
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.messages[r.URL.Path] = append(stub.messages[r.URL.Path], msg)
+		w.WriteHeader(stub.status)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func TestSlackNotify(t *testing.T) {
+	stub, server := newSlackStub(t)
+
+	slack := notify.NewSlack(server.URL+"/default", map[string]string{
+		"web": server.URL + "/frontend",
+	})
+
+	failure := github.WorkflowFailure{
+		RunID:      1,
+		Repo:       "api",
+		PRNumber:   42,
+		Workflow:   "CI",
+		Conclusion: "failure",
+		Actor:      "octocat",
+		StartedAt:  time.Date(2024, 3, 14, 9, 26, 53, 0, time.UTC),
+		URL:        "https://github.com/acme/api/actions/runs/1",
+		PRURL:      "https://github.com/acme/api/pull/42",
+	}
+	require.NoError(t, slack.Notify(context.Background(), notify.Event{Type: notify.EventFailure, Failure: failure}))
+
+	failure.RunID = 2
+	failure.Repo = "web"
+	require.NoError(t, slack.Notify(context.Background(), notify.Event{Type: notify.EventFailure, Failure: failure}))
+
+	require.Len(t, stub.messages["/default"], 1)
+	require.Len(t, stub.messages["/frontend"], 1)
+
+	msg := stub.messages["/default"][0]
+	assert.Equal(t, "CI failed in api: https://github.com/acme/api/actions/runs/1", msg["text"])
+
+	var encoded strings.Builder
+	enc := json.NewEncoder(&encoded)
+	enc.SetEscapeHTML(false)
+	require.NoError(t, enc.Encode(msg["blocks"]))
+	assert.Contains(t, encoded.String(), `"type":"header"`)
+	assert.Contains(t, encoded.String(), `*Pull request*\n<https://github.com/acme/api/pull/42|#42>`)
+	assert.Contains(t, encoded.String(), `*Author*\noctocat`)
+	assert.Contains(t, encoded.String(), `"url":"https://github.com/acme/api/actions/runs/1"`)
+
+	// Failures outside a pull request show their branch
+	failure.RunID = 3
+	failure.Repo = "api"
+	failure.PRNumber = 0
+	failure.Branch = "main"
+	require.NoError(t, slack.Notify(context.Background(), notify.Event{Type: notify.EventFailure, Failure: failure}))
+	require.Len(t, stub.messages["/default"], 2)
+	encoded.Reset()
+	require.NoError(t, enc.Encode(stub.messages["/default"][1]["blocks"]))
+	assert.Contains(t, encoded.String(), `*Branch*\nmain`)
+}
+
+func TestSlackNotifyUnrouted(t *testing.T) {
+	stub, server := newSlackStub(t)
+
+	slack := notify.NewSlack("", map[string]string{"web": server.URL + "/frontend"})
+	require.NoError(t, slack.Notify(context.Background(), notify.Event{Type: notify.EventFailure, Failure: github.WorkflowFailure{RunID: 1, Repo: "api"}}))
+
+	assert.Empty(t, stub.messages)
+}
+
+func TestSlackNotifyError(t *testing.T) {
+	stub, server := newSlackStub(t)
+	stub.status = http.StatusNotFound
+
+	slack := notify.NewSlack(server.URL, nil)
+	assert.Error(t, slack.Notify(context.Background(), notify.Event{Type: notify.EventFailure, Failure: github.WorkflowFailure{RunID: 1, Repo: "api"}}))
+}
+
+// memoryState is an in-memory notification state
+type memoryState struct {
+	notifications map[string]notify.Notification
+	// snoozed holds "repo/workflow" keys, or "repo/" for a whole repository
+	snoozed map[string]bool
+}
+
+func newMemoryState() *memoryState {
+	return &memoryState{notifications: make(map[string]notify.Notification), snoozed: make(map[string]bool)}
+}
+
+func (m *memoryState) Notified(notifier string, runID int64) (bool, error) {
+	_, ok := m.notifications[key(notifier, runID)]
+	return ok, nil
+}
+
+func (m *memoryState) MarkNotified(notifier string, n notify.Notification) error {
+	m.notifications[key(notifier, n.RunID)] = n
+	return nil
+}
+
+func (m *memoryState) Unresolved(notifier string) ([]notify.Notification, error) {
+	var result []notify.Notification
+	for k, n := range m.notifications {
+		if strings.HasPrefix(k, notifier+"/") && !n.Resolved() {
+			result = append(result, n)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.Before(result[j].StartedAt)
+	})
+	return result, nil
+}
+
+func (m *memoryState) MarkResolved(notifier string, runID int64, at time.Time) error {
+	n, ok := m.notifications[key(notifier, runID)]
+	if !ok {
+		return fmt.Errorf("run %d has not been announced", runID)
+	}
+	n.ResolvedAt = at
+	m.notifications[key(notifier, runID)] = n
+	return nil
+}
+
+func (m *memoryState) Snoozed(repo, workflow string, _ time.Time) (bool, error) {
+	return m.snoozed[repo+"/"] || m.snoozed[repo+"/"+workflow], nil
+}
+
+func key(notifier string, runID int64) string {
+	return fmt.Sprintf("%s/%d", notifier, runID)
+}
+
+func TestAnnounce(t *testing.T) {
+	stub, server := newSlackStub(t)
+	slack := notify.NewSlack(server.URL, nil)
+	state := newMemoryState()
+
+	now := time.Now()
+	failures := []github.WorkflowFailure{
+		{RunID: 2, Repo: "api", Workflow: "Lint", Branch: "main", StartedAt: now},
+		{RunID: 1, Repo: "api", Workflow: "CI", Branch: "feature", PRNumber: 5, StartedAt: now.Add(-time.Hour)},
+	}
+
+	sent, err := notify.Announce(context.Background(), slack, state, failures)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	// Oldest failure first
+	require.Len(t, stub.messages["/"], 2)
+	assert.Equal(t, "CI failed in api: ", stub.messages["/"][0]["text"])
+
+	// Announced failures are recorded so they can be resolved later
+	recorded := state.notifications[key(slack.Name(), 1)]
+	assert.Equal(t, "feature", recorded.Branch)
+	assert.Equal(t, 5, recorded.PRNumber)
+	assert.False(t, recorded.Snoozed)
+	assert.WithinDuration(t, time.Now(), recorded.AnnouncedAt, time.Minute)
+
+	// Repeat notifications for the same run are suppressed
+	sent, err = notify.Announce(context.Background(), slack, state, append(failures,
+		github.WorkflowFailure{RunID: 3, Repo: "api", Workflow: "CI", StartedAt: now}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Len(t, stub.messages["/"], 3)
+
+	// Other notifiers keep their own state
+	webhook, err := notify.NewWebhook(server.URL+"/webhook", notify.WebhookOptions{})
+	require.NoError(t, err)
+	sent, err = notify.Announce(context.Background(), webhook, state, failures)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.Len(t, stub.messages["/webhook"], 2)
+}
+
+func TestAnnounceRetriesFailedNotifications(t *testing.T) {
+	stub, server := newSlackStub(t)
+	stub.status = http.StatusInternalServerError
+	slack := notify.NewSlack(server.URL, nil)
+	state := newMemoryState()
+
+	failures := []github.WorkflowFailure{{RunID: 1, Repo: "api", Workflow: "CI"}}
+
+	sent, err := notify.Announce(context.Background(), slack, state, failures)
+	assert.Error(t, err)
+	assert.Equal(t, 0, sent)
+
+	// Failures that could not be sent are not recorded, and so are not resolved either
+	unresolved, err := state.Unresolved(slack.Name())
+	require.NoError(t, err)
+	assert.Empty(t, unresolved)
+
+	stub.status = http.StatusOK
+	sent, err = notify.Announce(context.Background(), slack, state, failures)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SignatureHeader carries the HMAC-SHA256 signature of signed webhook bodies
+const SignatureHeader = "X-Signature-256"
+
+// WebhookOptions configures a webhook notifier
+type WebhookOptions struct {
+	// Name identifies the webhook in the notification state; a hash of the URL is used when empty
+	Name string
+	// Template renders the request body from a Payload; the body is JSON when empty
+	Template string
+	// ContentType of the rendered body; defaults to application/json
+	ContentType string
+	// Headers are added to every request
+	Headers map[string]string
+	// Secret signs the body with HMAC-SHA256 in the X-Signature-256 header when set
+	Secret string
+	// Retries is the number of times a failed delivery is retried
+	Retries int
+	// Backoff is the delay before the first retry, doubling on every further attempt
+	Backoff time.Duration
+}
+
+// Webhook posts events to an arbitrary URL
+type Webhook struct {
+	URL string
+	// Client is the HTTP client used to post events; a client with a timeout is used when nil
+	Client *http.Client
+
+	opts     WebhookOptions
+	template *template.Template
+}
+
+// Payload is the data sent for an event, and the data available to templates
+type Payload struct {
+	Event      string    `json:"event"`
+	Repo       string    `json:"repo"`
+	Workflow   string    `json:"workflow"`
+	RunID      int64     `json:"run_id"`
+	Conclusion string    `json:"conclusion"`
+	HeadSHA    string    `json:"head_sha"`
+	Actor      string    `json:"actor,omitempty"`
+	PRNumber   int       `json:"pr_number,omitempty"`
+	PRURL      string    `json:"pr_url,omitempty"`
+	URL        string    `json:"url"`
+	StartedAt  time.Time `json:"started_at"`
+	// ResolvedURL links to the successful run that resolved the failure, for resolved events
+	ResolvedURL string `json:"resolved_url,omitempty"`
+}
+
+// templateFuncs are available to payload templates. json quotes a value so that
+// templates can build JSON bodies safely.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// NewWebhook creates a webhook notifier posting to url
+func NewWebhook(url string, opts WebhookOptions) (*Webhook, error) {
+	w := &Webhook{URL: url, opts: opts}
+
+	if opts.Template != "" {
+		tmpl, err := template.New("payload").Funcs(templateFuncs).Parse(opts.Template)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing payload template: %v", err)
+		}
+		w.template = tmpl
+	}
+	if w.opts.ContentType == "" {
+		w.opts.ContentType = "application/json"
+	}
+	if w.opts.Backoff <= 0 {
+		w.opts.Backoff = time.Second
+	}
+
+	return w, nil
+}
+
+// Name implements Notifier. Every URL keeps its own notification state; the URL is
+// hashed because it often carries a token.
+func (w *Webhook) Name() string {
+	if w.opts.Name != "" {
+		return "webhook:" + w.opts.Name
+	}
+	sum := sha256.Sum256([]byte(w.URL))
+	return "webhook:" + hex.EncodeToString(sum[:8])
+}
+
+// Notify implements Notifier, retrying failed deliveries with exponential backoff
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	body, err := w.render(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.opts.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// render builds the request body of an event
+func (w *Webhook) render(event Event) ([]byte, error) {
+	failure := event.Failure
+	payload := Payload{
+		Event:      event.Type,
+		Repo:       failure.Repo,
+		Workflow:   failure.Workflow,
+		RunID:      failure.RunID,
+		Conclusion: failure.Conclusion,
+		HeadSHA:    failure.HeadSHA,
+		Actor:      failure.Actor,
+		PRNumber:   failure.PRNumber,
+		PRURL:      failure.PRURL,
+		URL:        failure.URL,
+		StartedAt:  failure.StartedAt,
+	}
+	if event.Resolution != nil {
+		payload.ResolvedURL = event.Resolution.URL
+	}
+
+	if w.template == nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding webhook payload: %v", err)
+		}
+		return body, nil
+	}
+
+	var buf bytes.Buffer
+	if err := w.template.Execute(&buf, payload); err != nil {
+		return nil, fmt.Errorf("error rendering payload template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// post delivers a body once, reporting whether a failed delivery may be retried
+func (w *Webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("error creating webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", w.opts.ContentType)
+	for name, value := range w.opts.Headers {
+		req.Header.Set(name, value)
+	}
+	if w.opts.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign([]byte(w.opts.Secret), body))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("error posting webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("error posting webhook: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return false, nil
+}
+
+// Sign returns the signature of a body in the form "sha256=<hex digest>"
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,174 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEvent = notify.Event{
+	Type: notify.EventFailure,
+	Failure: github.WorkflowFailure{
+		RunID:      1,
+		Repo:       "api",
+		PRNumber:   42,
+		Workflow:   "CI",
+		Conclusion: "failure",
+		Actor:      "octocat",
+		StartedAt:  time.Date(2024, 3, 14, 9, 26, 53, 0, time.UTC),
+		URL:        "https://github.com/acme/api/actions/runs/1",
+		PRURL:      "https://github.com/acme/api/pull/42",
+	},
+}
+
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) (*httptest.Server, *[]delivery) {
+	var deliveries []delivery
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		deliveries = append(deliveries, delivery{header: r.Header, body: body})
+
+		status := http.StatusOK
+		if len(deliveries) <= len(statuses) {
+			status = statuses[len(deliveries)-1]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &deliveries
+}
+
+func TestWebhookNotifyJSON(t *testing.T) {
+	server, deliveries := newWebhookServer(t)
+
+	webhook, err := notify.NewWebhook(server.URL, notify.WebhookOptions{
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Secret:  "secret",
+	})
+	require.NoError(t, err)
+	require.NoError(t, webhook.Notify(context.Background(), testEvent))
+
+	require.Len(t, *deliveries, 1)
+	got := (*deliveries)[0]
+	assert.Equal(t, "application/json", got.header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", got.header.Get("Authorization"))
+	assert.Equal(t, notify.Sign([]byte("secret"), got.body), got.header.Get(notify.SignatureHeader))
+
+	var payload notify.Payload
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	assert.Equal(t, notify.Payload{
+		Event:      "failure",
+		Repo:       "api",
+		Workflow:   "CI",
+		RunID:      1,
+		Conclusion: "failure",
+		Actor:      "octocat",
+		PRNumber:   42,
+		PRURL:      "https://github.com/acme/api/pull/42",
+		URL:        "https://github.com/acme/api/actions/runs/1",
+		StartedAt:  testEvent.Failure.StartedAt,
+	}, payload)
+}
+
+func TestWebhookNotifyTemplate(t *testing.T) {
+	server, deliveries := newWebhookServer(t)
+
+	webhook, err := notify.NewWebhook(server.URL, notify.WebhookOptions{
+		Template: `{"content": {{json (printf "%s %s in %s: %s" .Workflow .Event .Repo .URL)}}}`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, webhook.Notify(context.Background(), testEvent))
+
+	require.Len(t, *deliveries, 1)
+	got := (*deliveries)[0]
+	assert.Empty(t, got.header.Get(notify.SignatureHeader))
+	assert.JSONEq(t, `{"content": "CI failure in api: https://github.com/acme/api/actions/runs/1"}`, string(got.body))
+}
+
+func TestWebhookInvalidTemplate(t *testing.T) {
+	_, err := notify.NewWebhook("http://localhost", notify.WebhookOptions{Template: "{{.Repo"})
+	assert.Error(t, err)
+}
+
+func TestWebhookNotifyRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		retries    int
+		wantErr    bool
+		wantPosted int
+	}{
+		{
+			name:       "recovers after server errors",
+			statuses:   []int{http.StatusBadGateway, http.StatusTooManyRequests},
+			retries:    3,
+			wantPosted: 3,
+		},
+		{
+			name:       "gives up after the last retry",
+			statuses:   []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			retries:    2,
+			wantErr:    true,
+			wantPosted: 3,
+		},
+		{
+			name:       "client errors are not retried",
+			statuses:   []int{http.StatusBadRequest},
+			retries:    3,
+			wantErr:    true,
+			wantPosted: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, deliveries := newWebhookServer(t, tt.statuses...)
+
+			webhook, err := notify.NewWebhook(server.URL, notify.WebhookOptions{
+				Retries: tt.retries,
+				Backoff: time.Millisecond,
+			})
+			require.NoError(t, err)
+
+			err = webhook.Notify(context.Background(), testEvent)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, *deliveries, tt.wantPosted)
+		})
+	}
+}
+
+func TestWebhookName(t *testing.T) {
+	url := "https://hooks.example.com/services/secret-token"
+
+	webhook, err := notify.NewWebhook(url, notify.WebhookOptions{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(webhook.Name(), "webhook:"))
+	assert.NotContains(t, webhook.Name(), "secret-token")
+
+	other, err := notify.NewWebhook(url+"-rotated", notify.WebhookOptions{})
+	require.NoError(t, err)
+	assert.NotEqual(t, webhook.Name(), other.Name())
+
+	named, err := notify.NewWebhook(url, notify.WebhookOptions{Name: "pagerduty"})
+	require.NoError(t, err)
+	assert.Equal(t, "webhook:pagerduty", named.Name())
+}
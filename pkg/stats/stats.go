@@ -0,0 +1,220 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+)
+
+// Grouping and sort keys
+const (
+	ByRepo     = "repo"
+	ByWorkflow = "workflow"
+
+	SortName     = "name"
+	SortRuns     = "runs"
+	SortFailures = "failures"
+	SortRate     = "rate"
+	SortDuration = "duration"
+	SortP95      = "p95"
+	SortMTTR     = "mttr"
+)
+
+// Row holds the aggregated statistics of a repository or workflow
+type Row struct {
+	Repo     string
+	Workflow string
+	Runs     int
+	Failures int
+	// FailureRate is the fraction of runs that failed, between 0 and 1
+	FailureRate  float64
+	MeanDuration time.Duration
+	P95Duration  time.Duration
+	// MTTR is the mean time from the first failure of an outage to the next success
+	MTTR       time.Duration
+	Recoveries int
+}
+
+// Aggregate computes statistics for the runs grouped by repository or by workflow.
+// Only runs that failed or succeeded are counted.
+func Aggregate(runs []github.RunSummary, by string) ([]Row, error) {
+	if by != ByRepo && by != ByWorkflow {
+		return nil, fmt.Errorf("unknown grouping %q", by)
+	}
+
+	type group struct {
+		row       Row
+		durations []time.Duration
+		recovery  []time.Duration
+	}
+
+	var order []string
+	groups := make(map[string]*group)
+	for _, run := range sortedConclusive(runs) {
+		key := run.Repo
+		if by == ByWorkflow {
+			key += "\x00" + run.Workflow
+		}
+
+		g, ok := groups[key]
+		if !ok {
+			g = &group{row: Row{Repo: run.Repo}}
+			if by == ByWorkflow {
+				g.row.Workflow = run.Workflow
+			}
+			groups[key] = g
+			order = append(order, key)
+		}
+
+		g.row.Runs++
+		if run.Failed() {
+			g.row.Failures++
+		}
+		if d := run.Duration(); d > 0 {
+			g.durations = append(g.durations, d)
+		}
+	}
+
+	for _, r := range recoveries(runs) {
+		key := r.repo
+		if by == ByWorkflow {
+			key += "\x00" + r.workflow
+		}
+		if g, ok := groups[key]; ok {
+			g.recovery = append(g.recovery, r.duration)
+		}
+	}
+
+	rows := make([]Row, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		g.row.FailureRate = float64(g.row.Failures) / float64(g.row.Runs)
+		g.row.MeanDuration = mean(g.durations)
+		g.row.P95Duration = percentile(g.durations, 0.95)
+		g.row.MTTR = mean(g.recovery)
+		g.row.Recoveries = len(g.recovery)
+		rows = append(rows, g.row)
+	}
+
+	return rows, nil
+}
+
+// Sort orders rows by the given key. Numeric keys sort descending, names ascending.
+func Sort(rows []Row, key string) error {
+	var less func(a, b Row) bool
+	switch key {
+	case SortName:
+		less = func(a, b Row) bool {
+			if a.Repo != b.Repo {
+				return a.Repo < b.Repo
+			}
+			return a.Workflow < b.Workflow
+		}
+	case SortRuns:
+		less = func(a, b Row) bool { return a.Runs > b.Runs }
+	case SortFailures:
+		less = func(a, b Row) bool { return a.Failures > b.Failures }
+	case SortRate:
+		less = func(a, b Row) bool { return a.FailureRate > b.FailureRate }
+	case SortDuration:
+		less = func(a, b Row) bool { return a.MeanDuration > b.MeanDuration }
+	case SortP95:
+		less = func(a, b Row) bool { return a.P95Duration > b.P95Duration }
+	case SortMTTR:
+		less = func(a, b Row) bool { return a.MTTR > b.MTTR }
+	default:
+		return fmt.Errorf("unknown sort key %q", key)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return less(rows[i], rows[j])
+	})
+	return nil
+}
+
+// recovery is a single outage of a workflow on a branch that ended in a success
+type recovery struct {
+	repo     string
+	workflow string
+	duration time.Duration
+}
+
+// recoveries finds every outage that was recovered from. Runs are tracked per
+// workflow and branch, so a red PR branch does not affect the default branch.
+func recoveries(runs []github.RunSummary) []recovery {
+	type track struct {
+		repo, workflow, branch string
+	}
+
+	var result []recovery
+	failingSince := make(map[track]time.Time)
+
+	// Oldest first
+	sorted := sortedConclusive(runs)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		run := sorted[i]
+		key := track{run.Repo, run.Workflow, run.Branch}
+
+		since, failing := failingSince[key]
+		switch {
+		case run.Failed() && !failing:
+			failingSince[key] = run.CreatedAt
+		case run.Succeeded() && failing:
+			result = append(result, recovery{
+				repo:     run.Repo,
+				workflow: run.Workflow,
+				duration: run.CreatedAt.Sub(since),
+			})
+			delete(failingSince, key)
+		}
+	}
+
+	return result
+}
+
+// sortedConclusive returns the runs that failed or succeeded, newest first
+func sortedConclusive(runs []github.RunSummary) []github.RunSummary {
+	var result []github.RunSummary
+	for _, run := range runs {
+		if run.Failed() || run.Succeeded() {
+			result = append(result, run)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+func mean(values []time.Duration) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, v := range values {
+		total += v
+	}
+	return total / time.Duration(len(values))
+}
+
+// percentile returns the nearest-rank percentile of the values
+func percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,100 @@
+package stats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRuns() []github.RunSummary {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := func(repo, workflow, branch, conclusion string, hour int, minutes int) github.RunSummary {
+		created := base.Add(time.Duration(hour) * time.Hour)
+		return github.RunSummary{
+			Repo:       repo,
+			Workflow:   workflow,
+			Branch:     branch,
+			Conclusion: conclusion,
+			CreatedAt:  created,
+			StartedAt:  created,
+			UpdatedAt:  created.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+
+	return []github.RunSummary{
+		run("repo1", "CI", "main", "success", 0, 10),
+		run("repo1", "CI", "main", "failure", 1, 20),
+		run("repo1", "CI", "feature", "failure", 2, 30),
+		run("repo1", "CI", "main", "failure", 3, 40),
+		run("repo1", "CI", "main", "success", 5, 50),
+		run("repo1", "Lint", "main", "success", 1, 1),
+		run("repo1", "Lint", "main", "cancelled", 2, 1),
+		run("repo2", "CI", "main", "failure", 1, 5),
+	}
+}
+
+func TestAggregateByWorkflow(t *testing.T) {
+	rows, err := stats.Aggregate(testRuns(), stats.ByWorkflow)
+	require.NoError(t, err)
+	require.NoError(t, stats.Sort(rows, stats.SortName))
+	require.Len(t, rows, 3)
+
+	ci := rows[0]
+	assert.Equal(t, "repo1", ci.Repo)
+	assert.Equal(t, "CI", ci.Workflow)
+	assert.Equal(t, 5, ci.Runs)
+	assert.Equal(t, 3, ci.Failures)
+	assert.InDelta(t, 0.6, ci.FailureRate, 0.0001)
+	assert.Equal(t, 30*time.Minute, ci.MeanDuration)
+	assert.Equal(t, 50*time.Minute, ci.P95Duration)
+	// The main branch outage started at hour 1 and recovered at hour 5; the feature branch never recovered
+	assert.Equal(t, 1, ci.Recoveries)
+	assert.Equal(t, 4*time.Hour, ci.MTTR)
+
+	lint := rows[1]
+	assert.Equal(t, "Lint", lint.Workflow)
+	assert.Equal(t, 1, lint.Runs)
+	assert.Equal(t, 0, lint.Failures)
+	assert.Equal(t, 0, lint.Recoveries)
+
+	assert.Equal(t, "repo2", rows[2].Repo)
+	assert.Equal(t, 1.0, rows[2].FailureRate)
+}
+
+func TestAggregateByRepo(t *testing.T) {
+	rows, err := stats.Aggregate(testRuns(), stats.ByRepo)
+	require.NoError(t, err)
+	require.NoError(t, stats.Sort(rows, stats.SortRuns))
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, "repo1", rows[0].Repo)
+	assert.Empty(t, rows[0].Workflow)
+	assert.Equal(t, 6, rows[0].Runs)
+	assert.Equal(t, 3, rows[0].Failures)
+	assert.Equal(t, 1, rows[0].Recoveries)
+
+	_, err = stats.Aggregate(testRuns(), "branch")
+	assert.Error(t, err)
+}
+
+func TestSort(t *testing.T) {
+	rows := []stats.Row{
+		{Repo: "b", Failures: 1, FailureRate: 0.5, MTTR: time.Hour},
+		{Repo: "a", Failures: 3, FailureRate: 0.1, MTTR: time.Minute},
+	}
+
+	require.NoError(t, stats.Sort(rows, stats.SortRate))
+	assert.Equal(t, "b", rows[0].Repo)
+
+	require.NoError(t, stats.Sort(rows, stats.SortFailures))
+	assert.Equal(t, "a", rows[0].Repo)
+
+	require.NoError(t, stats.Sort(rows, stats.SortMTTR))
+	assert.Equal(t, "b", rows[0].Repo)
+
+	assert.Error(t, stats.Sort(rows, "unknown"))
+}
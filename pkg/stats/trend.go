@@ -0,0 +1,112 @@
+package stats
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+)
+
+var (
+	unicodeLevels = []rune("▁▂▃▄▅▆▇█")
+	asciiLevels   = []rune("_.-:=+*#")
+)
+
+// Trend holds the number of failed runs per day for a repository or workflow
+type Trend struct {
+	Repo     string
+	Workflow string
+	// Daily holds one count per day, oldest first, ending with the day of now
+	Daily []int
+}
+
+// Total returns the number of failures across the whole trend
+func (t Trend) Total() int {
+	total := 0
+	for _, count := range t.Daily {
+		total += count
+	}
+	return total
+}
+
+// DailyFailures counts failed runs per day over the last days days, grouped by repository or workflow
+func DailyFailures(runs []github.RunSummary, by string, days int, now time.Time) []Trend {
+	if days < 1 {
+		return nil
+	}
+
+	today := startOfDay(now)
+	first := today.AddDate(0, 0, -(days - 1))
+
+	var order []string
+	trends := make(map[string]*Trend)
+	for _, run := range sortedConclusive(runs) {
+		key := run.Repo
+		if by == ByWorkflow {
+			key += "\x00" + run.Workflow
+		}
+
+		trend, ok := trends[key]
+		if !ok {
+			trend = &Trend{Repo: run.Repo, Daily: make([]int, days)}
+			if by == ByWorkflow {
+				trend.Workflow = run.Workflow
+			}
+			trends[key] = trend
+			order = append(order, key)
+		}
+
+		if !run.Failed() {
+			continue
+		}
+
+		day := startOfDay(run.CreatedAt.In(now.Location()))
+		if day.Before(first) || day.After(today) {
+			continue
+		}
+		// Count calendar days rather than 24h periods so DST changes don't shift buckets
+		index := 0
+		for d := first; d.Before(day); d = d.AddDate(0, 0, 1) {
+			index++
+		}
+		trend.Daily[index]++
+	}
+
+	result := make([]Trend, 0, len(order))
+	for _, key := range order {
+		result = append(result, *trends[key])
+	}
+	return result
+}
+
+// Sparkline renders counts as a single line of bar characters scaled to the largest count.
+// Days without failures are rendered as spaces so that red days stand out.
+func Sparkline(counts []int, ascii bool) string {
+	levels := unicodeLevels
+	if ascii {
+		levels = asciiLevels
+	}
+
+	max := 0
+	for _, count := range counts {
+		if count > max {
+			max = count
+		}
+	}
+
+	var b strings.Builder
+	for _, count := range counts {
+		if count == 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		level := (count*len(levels) - 1) / max
+		b.WriteRune(levels[level])
+	}
+	return b.String()
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
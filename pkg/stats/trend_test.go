@@ -0,0 +1,50 @@
+package stats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyFailures(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	run := func(repo, workflow, conclusion string, daysAgo int) github.RunSummary {
+		return github.RunSummary{
+			Repo:       repo,
+			Workflow:   workflow,
+			Conclusion: conclusion,
+			CreatedAt:  now.AddDate(0, 0, -daysAgo),
+		}
+	}
+
+	runs := []github.RunSummary{
+		run("repo1", "CI", "failure", 0),
+		run("repo1", "CI", "failure", 0),
+		run("repo1", "CI", "success", 1),
+		run("repo1", "Lint", "failure", 2),
+		run("repo1", "CI", "failure", 10),
+		run("repo2", "CI", "success", 1),
+	}
+
+	trends := stats.DailyFailures(runs, stats.ByRepo, 3, now)
+	if assert.Len(t, trends, 2) {
+		assert.Equal(t, "repo1", trends[0].Repo)
+		assert.Equal(t, []int{1, 0, 2}, trends[0].Daily)
+		assert.Equal(t, 3, trends[0].Total())
+
+		assert.Equal(t, "repo2", trends[1].Repo)
+		assert.Equal(t, []int{0, 0, 0}, trends[1].Daily)
+	}
+
+	trends = stats.DailyFailures(runs, stats.ByWorkflow, 3, now)
+	assert.Len(t, trends, 3)
+}
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, " ▁█", stats.Sparkline([]int{0, 1, 8}, false))
+	assert.Equal(t, "#  #", stats.Sparkline([]int{2, 0, 0, 2}, true))
+	assert.Equal(t, "   ", stats.Sparkline([]int{0, 0, 0}, false))
+}
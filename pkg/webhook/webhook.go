@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	gh "github.com/google/go-github/v60/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+)
+
+// actionsAppSlug identifies check suites created by GitHub Actions, which are already
+// reported through workflow_run events
+const actionsAppSlug = "github-actions"
+
+// queueSize is how many failures may wait for the sinks before deliveries are refused
+const queueSize = 256
+
+// Sink consumes workflow failures
+type Sink interface {
+	Observe(failure github.WorkflowFailure)
+}
+
+// SinkFunc adapts a function to the Sink interface
+type SinkFunc func(failure github.WorkflowFailure)
+
+// Observe implements Sink
+func (f SinkFunc) Observe(failure github.WorkflowFailure) {
+	f(failure)
+}
+
+// Handler receives GitHub workflow_run and check_suite webhook deliveries, verifies
+// their signatures and passes failed runs to its sinks. Deliveries are acknowledged
+// straight away and the sinks are called from a background worker, since GitHub gives
+// up on deliveries that take longer than 10 seconds.
+type Handler struct {
+	secret []byte
+	sinks  []Sink
+
+	queue chan github.WorkflowFailure
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewHandler creates a webhook handler verifying deliveries with the given secret. Call
+// Close once the handler no longer serves requests.
+func NewHandler(secret string, sinks ...Sink) *Handler {
+	h := &Handler{
+		secret: []byte(secret),
+		sinks:  sinks,
+		queue:  make(chan github.WorkflowFailure, queueSize),
+		done:   make(chan struct{}),
+	}
+	go h.run()
+	return h
+}
+
+// Close waits until every queued failure has been passed to the sinks. The handler
+// must not serve requests afterwards.
+func (h *Handler) Close() {
+	h.once.Do(func() { close(h.queue) })
+	<-h.done
+}
+
+// run passes queued failures to the sinks until the handler is closed
+func (h *Handler) run() {
+	defer close(h.done)
+	for failure := range h.queue {
+		for _, sink := range h.sinks {
+			sink.Observe(failure)
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Validates the X-Hub-Signature-256 HMAC of the body
+	payload, err := gh.ValidatePayload(r, h.secret)
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	failure, err := ParseEvent(gh.WebHookType(r), payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if failure != nil {
+		select {
+		case h.queue <- *failure:
+		default:
+			http.Error(w, "too many pending deliveries", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ParseEvent converts a webhook payload into a workflow failure. It returns nil
+// when the event is not a completed, failed run or is of an unsupported type.
+func ParseEvent(eventType string, payload []byte) (*github.WorkflowFailure, error) {
+	if eventType != "workflow_run" && eventType != "check_suite" {
+		return nil, nil
+	}
+
+	event, err := gh.ParseWebHook(eventType, payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %v", eventType, err)
+	}
+
+	switch e := event.(type) {
+	case *gh.WorkflowRunEvent:
+		run := e.GetWorkflowRun()
+		if e.GetAction() != "completed" || !isFailure(run.GetConclusion()) {
+			return nil, nil
+		}
+
+		failure := &github.WorkflowFailure{
+			RunID:      run.GetID(),
+			Repo:       e.GetRepo().GetName(),
+			Workflow:   run.GetName(),
+			Conclusion: run.GetConclusion(),
+			HeadSHA:    run.GetHeadSHA(),
+			Branch:     run.GetHeadBranch(),
+			Actor:      run.GetActor().GetLogin(),
+			StartedAt:  run.GetCreatedAt().Time,
+			UpdatedAt:  run.GetUpdatedAt().Time,
+			URL:        run.GetHTMLURL(),
+		}
+		if len(run.PullRequests) > 0 {
+			failure.PRNumber = run.PullRequests[0].GetNumber()
+			failure.PRURL = fmt.Sprintf("%s/pull/%d", e.GetRepo().GetHTMLURL(), failure.PRNumber)
+		}
+		return failure, nil
+
+	case *gh.CheckSuiteEvent:
+		suite := e.GetCheckSuite()
+		if e.GetAction() != "completed" || !isFailure(suite.GetConclusion()) || suite.GetApp().GetSlug() == actionsAppSlug {
+			return nil, nil
+		}
+
+		failure := &github.WorkflowFailure{
+			CheckSuiteID: suite.GetID(),
+			Repo:         e.GetRepo().GetName(),
+			Workflow:     suite.GetApp().GetName(),
+			Conclusion:   suite.GetConclusion(),
+			HeadSHA:      suite.GetHeadSHA(),
+			Branch:       suite.GetHeadBranch(),
+			StartedAt:    suite.GetCreatedAt().Time,
+			UpdatedAt:    suite.GetUpdatedAt().Time,
+			URL:          fmt.Sprintf("%s/commit/%s/checks", e.GetRepo().GetHTMLURL(), suite.GetHeadSHA()),
+		}
+		if len(suite.PullRequests) > 0 {
+			failure.PRNumber = suite.PullRequests[0].GetNumber()
+			failure.PRURL = fmt.Sprintf("%s/pull/%d", e.GetRepo().GetHTMLURL(), failure.PRNumber)
+		}
+		return failure, nil
+	}
+
+	return nil, nil
+}
+
+// LogSink logs every failure it observes
+var LogSink = SinkFunc(func(failure github.WorkflowFailure) {
+	log.Printf("Workflow failure: %s / %s (%s) %s", failure.Repo, failure.Workflow, failure.Conclusion, failure.URL)
+})
+
+func isFailure(conclusion string) bool {
+	return github.RunSummary{Conclusion: conclusion}.Failed()
+}
@@ -0,0 +1,205 @@
+package webhook_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/github"
+	"github.com/kjkondratuk/gh-workflow-monitor/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "It's a Secret to Everybody"
+
+type recorder struct {
+	failures []github.WorkflowFailure
+}
+
+func (r *recorder) Observe(failure github.WorkflowFailure) {
+	r.failures = append(r.failures, failure)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		event      string
+		file       string
+		secret     string
+		wantStatus int
+		want       []github.WorkflowFailure
+	}{
+		{
+			name:       "failed workflow run",
+			event:      "workflow_run",
+			file:       "workflow_run_failure.json",
+			secret:     secret,
+			wantStatus: http.StatusNoContent,
+			want: []github.WorkflowFailure{
+				{
+					RunID:      9876543210,
+					Repo:       "api",
+					PRNumber:   42,
+					Workflow:   "CI",
+					Conclusion: "failure",
+					HeadSHA:    "5d3f1c2a9b8e7d6c5b4a39281706f5e4d3c2b1a0",
+					Branch:     "feature/login",
+					Actor:      "octocat",
+					StartedAt:  time.Date(2024, 3, 14, 9, 26, 53, 0, time.UTC),
+					UpdatedAt:  time.Date(2024, 3, 14, 9, 31, 7, 0, time.UTC),
+					URL:        "https://github.com/acme/api/actions/runs/9876543210",
+					PRURL:      "https://github.com/acme/api/pull/42",
+				},
+			},
+		},
+		{
+			name:       "successful workflow run",
+			event:      "workflow_run",
+			file:       "workflow_run_success.json",
+			secret:     secret,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "workflow run in progress",
+			event:      "workflow_run",
+			file:       "workflow_run_in_progress.json",
+			secret:     secret,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "failed check suite",
+			event:      "check_suite",
+			file:       "check_suite_failure.json",
+			secret:     secret,
+			wantStatus: http.StatusNoContent,
+			want: []github.WorkflowFailure{
+				{
+					CheckSuiteID: 22334455667,
+					Repo:         "web",
+					PRNumber:     7,
+					Workflow:     "CircleCI Checks",
+					Conclusion:   "timed_out",
+					HeadSHA:      "9f8e7d6c5b4a39281706f5e4d3c2b1a00a1b2c3d",
+					Branch:       "feature/cache",
+					StartedAt:    time.Date(2024, 3, 14, 11, 0, 0, 0, time.UTC),
+					UpdatedAt:    time.Date(2024, 3, 14, 11, 45, 0, 0, time.UTC),
+					URL:          "https://github.com/acme/web/commit/9f8e7d6c5b4a39281706f5e4d3c2b1a00a1b2c3d/checks",
+					PRURL:        "https://github.com/acme/web/pull/7",
+				},
+			},
+		},
+		{
+			name:       "check suite of GitHub Actions is reported by workflow_run instead",
+			event:      "check_suite",
+			file:       "check_suite_actions.json",
+			secret:     secret,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "unsupported event",
+			event:      "push",
+			file:       "workflow_run_failure.json",
+			secret:     secret,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "invalid signature",
+			event:      "workflow_run",
+			file:       "workflow_run_failure.json",
+			secret:     "wrong secret",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recorder{}
+			handler := webhook.NewHandler(secret, sink)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, delivery(t, tt.event, tt.file, tt.secret))
+			handler.Close()
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.want, normalize(sink.failures))
+		})
+	}
+}
+
+func TestHandlerRejectsUnsignedDelivery(t *testing.T) {
+	sink := &recorder{}
+	handler := webhook.NewHandler(secret, sink)
+
+	payload, err := os.ReadFile(filepath.Join("testdata", "workflow_run_failure.json"))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "workflow_run")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	handler.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, sink.failures)
+}
+
+func TestHandlerRepliesBeforeSinks(t *testing.T) {
+	release := make(chan struct{})
+	sink := &recorder{}
+	handler := webhook.NewHandler(secret, webhook.SinkFunc(func(failure github.WorkflowFailure) {
+		<-release
+		sink.Observe(failure)
+	}))
+
+	// The delivery is acknowledged while the sink is still busy
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, delivery(t, "workflow_run", "workflow_run_failure.json", secret))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// Closing waits for the queued failure to reach the sink
+	close(release)
+	handler.Close()
+	require.Len(t, sink.failures, 1)
+	assert.Equal(t, int64(9876543210), sink.failures[0].RunID)
+}
+
+func TestParseEventInvalidPayload(t *testing.T) {
+	_, err := webhook.ParseEvent("workflow_run", []byte("{"))
+	assert.Error(t, err)
+}
+
+// delivery replays a recorded payload the way GitHub delivers it
+func delivery(t *testing.T, event, file, key string) *http.Request {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("testdata", file))
+	require.NoError(t, err)
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+// normalize converts times to UTC so that failures compare equal regardless of location
+func normalize(failures []github.WorkflowFailure) []github.WorkflowFailure {
+	for i := range failures {
+		failures[i].StartedAt = failures[i].StartedAt.UTC()
+		failures[i].UpdatedAt = failures[i].UpdatedAt.UTC()
+	}
+	return failures
+}